@@ -0,0 +1,145 @@
+// Package board reads and writes Markdown task boards: one "## " heading
+// per column and one checkbox line per task, carrying the task id in an
+// HTML comment so the line can be matched after it was edited or moved.
+package board
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Item is one checkbox line. ID is empty for lines added by hand.
+type Item struct {
+	ID   string
+	Done bool
+	Text string
+	Note string
+}
+
+type Column struct {
+	Name  string
+	Items []Item
+}
+
+type Board struct {
+	Title   string
+	Columns []Column
+}
+
+// Column returns the column with the given name, ignoring case.
+func (b *Board) Column(name string) *Column {
+	for i := range b.Columns {
+		if strings.EqualFold(b.Columns[i].Name, name) {
+			return &b.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Render writes b as Markdown. Notes are rendered in italics after the
+// text, Parse strips them again.
+func Render(w io.Writer, b Board) error {
+	bw := bufio.NewWriter(w)
+
+	if b.Title != "" {
+		fmt.Fprintf(bw, "# %s\n\n", b.Title)
+	}
+	for i, c := range b.Columns {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "## %s\n\n", c.Name)
+		for _, it := range c.Items {
+			bw.WriteString(renderItem(it))
+			bw.WriteString("\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+func renderItem(it Item) string {
+	box := " "
+	if it.Done {
+		box = "x"
+	}
+
+	line := fmt.Sprintf("- [%s] %s", box, escapeText(it.Text))
+	if it.Note != "" {
+		line += " _(" + it.Note + ")_"
+	}
+	if it.ID != "" {
+		line += " <!-- id:" + it.ID + " -->"
+	}
+	return line
+}
+
+// escapeText keeps task names from being read back as a note or an id.
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	s = strings.ReplaceAll(s, "<!--", "<\\!--")
+	s = strings.ReplaceAll(s, "_(", "\\_(")
+	return s
+}
+
+func unescapeText(s string) string {
+	s = strings.ReplaceAll(s, "\\_(", "_(")
+	s = strings.ReplaceAll(s, "<\\!--", "<!--")
+	return s
+}
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,2})\s+(.*?)\s*$`)
+	itemRe    = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s*(.*?)\s*$`)
+	idRe      = regexp.MustCompile(`\s*<!--\s*id:\s*([^\s]+)\s*-->\s*$`)
+	noteRe    = regexp.MustCompile(`(?:^|\s+)_\([^)]*\)_\s*$`)
+)
+
+// Parse reads a board. Lines other than headings and checkbox items are
+// ignored, as are items before the first column.
+func Parse(r io.Reader) (Board, error) {
+	var b Board
+	var current *Column
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			if m[1] == "#" {
+				b.Title = m[2]
+				continue
+			}
+			b.Columns = append(b.Columns, Column{Name: m[2]})
+			current = &b.Columns[len(b.Columns)-1]
+			continue
+		}
+
+		m := itemRe.FindStringSubmatch(line)
+		if m == nil || current == nil {
+			continue
+		}
+
+		it := Item{Done: m[1] != " "}
+		text := m[2]
+		if idm := idRe.FindStringSubmatch(text); idm != nil {
+			it.ID = idm[1]
+			text = text[:len(text)-len(idm[0])]
+		}
+		if nm := noteRe.FindString(text); nm != "" {
+			it.Note = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(nm), "_("), ")_")
+			text = text[:len(text)-len(nm)]
+		}
+		it.Text = unescapeText(strings.TrimSpace(text))
+
+		if it.Text == "" && it.ID == "" {
+			continue
+		}
+		current.Items = append(current.Items, it)
+	}
+
+	return b, sc.Err()
+}
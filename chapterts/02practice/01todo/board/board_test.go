@@ -0,0 +1,94 @@
+package board
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	b := Board{
+		Title: "Tasks",
+		Columns: []Column{
+			{Name: "Todo", Items: []Item{
+				{ID: "a1", Text: "Send invoice", Note: "due 2025-04-18 10:30"},
+				{ID: "b2", Text: "Review <!-- notes"},
+				{ID: "d4", Text: "Rename _(draft)_"},
+			}},
+			{Name: "Done", Items: []Item{{ID: "c3", Done: true, Text: "Buy milk"}}},
+		},
+	}
+
+	var sb strings.Builder
+	if err := Render(&sb, b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Tasks
+
+## Todo
+
+- [ ] Send invoice _(due 2025-04-18 10:30)_ <!-- id:a1 -->
+- [ ] Review <\!-- notes <!-- id:b2 -->
+- [ ] Rename \_(draft)_ <!-- id:d4 -->
+
+## Done
+
+- [x] Buy milk <!-- id:c3 -->
+`
+	if sb.String() != expected {
+		t.Errorf("unexpected board:\n%s\nexpected:\n%s", sb.String(), expected)
+	}
+
+	parsed, err := Parse(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, b) {
+		t.Errorf("parse of rendered board differs:\n%+v\nexpected:\n%+v", parsed, b)
+	}
+}
+
+func TestParse_Edited(t *testing.T) {
+	input := `# Tasks
+
+- [ ] before any column is ignored
+
+## Todo
+
+Some text in between.
+
+* [X] Send invoice _(due tomorrow)_ <!-- id:a1 -->
+- [ ]   New task from the file
+- [ ]
+
+## In progress
+  - [ ] Buy milk<!--id:c3-->
+`
+
+	b, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Board{
+		Title: "Tasks",
+		Columns: []Column{
+			{Name: "Todo", Items: []Item{
+				{ID: "a1", Done: true, Text: "Send invoice", Note: "due tomorrow"},
+				{Text: "New task from the file"},
+			}},
+			{Name: "In progress", Items: []Item{{ID: "c3", Text: "Buy milk"}}},
+		},
+	}
+	if !reflect.DeepEqual(b, expected) {
+		t.Errorf("unexpected board:\n%+v\nexpected:\n%+v", b, expected)
+	}
+
+	if c := b.Column("in PROGRESS"); c == nil || c.Name != "In progress" {
+		t.Errorf("column lookup must ignore case, got %+v", c)
+	}
+	if b.Column("Done") != nil {
+		t.Errorf("expected no Done column")
+	}
+}
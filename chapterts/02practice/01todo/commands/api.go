@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"todo/cli/db"
+	"todo/cli/hooks"
+)
+
+type apiTask struct {
+	taskView
+	Archived bool `json:"archived"`
+}
+
+type taskInput struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"desc"`
+	Time        *dueTime  `json:"time"`
+	Tags        *[]string `json:"tags"`
+	List        *string   `json:"list"`
+}
+
+// apiMux serves tasks of s as JSON over HTTP. Mutations run the same hooks
+// as CLI commands and are saved immediately.
+func apiMux(s *db.Storage) *http.ServeMux {
+	mux := http.NewServeMux()
+	api := &taskAPI{s}
+
+	mux.HandleFunc("GET /tasks", api.list)
+	mux.HandleFunc("POST /tasks", api.create)
+	mux.HandleFunc("GET /tasks/{id}", api.get)
+	mux.HandleFunc("PUT /tasks/{id}", api.replace)
+	mux.HandleFunc("PATCH /tasks/{id}", api.patch)
+	mux.HandleFunc("DELETE /tasks/{id}", api.delete)
+	mux.HandleFunc("POST /tasks/{id}/done", api.markDone)
+
+	return mux
+}
+
+type taskAPI struct {
+	s *db.Storage
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{
+		"error":   code,
+		"message": message,
+	})
+}
+
+// view shows t with its short id from short, which callers compute once
+// per request as ShortIDs sorts every id.
+func view(short map[string]string, t *db.Task, archived bool) apiTask {
+	return apiTask{taskView{short[t.ID.String()], t}, archived}
+}
+
+func (a *taskAPI) save(w http.ResponseWriter) bool {
+	if err := a.s.Save(); err != nil {
+		logger.Warn("Failed save tasks", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "failed to save tasks")
+		return false
+	}
+	return true
+}
+
+// resolve writes an error response and returns false when the path id
+// doesn't reference exactly one task.
+func (a *taskAPI) resolve(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, err := a.s.ResolveID(r.PathValue("id"))
+	if err == nil {
+		return id, true
+	}
+
+	var ambiguous *db.AmbiguousIDError
+	if errors.As(err, &ambiguous) {
+		writeError(w, http.StatusConflict, "ambiguous_id", err.Error())
+	} else {
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	}
+	return "", false
+}
+
+func (a *taskAPI) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	archived, err := queryBool(q.Get("archived"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid archived: "+err.Error())
+		return
+	}
+	var done *bool
+	if v := q.Get("done"); v != "" {
+		d, err := queryBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "invalid done: "+err.Error())
+			return
+		}
+		done = &d
+	}
+	var dueBefore, dueAfter time.Time
+	for name, target := range map[string]*time.Time{"due_before": &dueBefore, "due_after": &dueAfter} {
+		if v := q.Get(name); v != "" {
+			if *target, err = dueParser.Parse(v); err != nil {
+				writeError(w, http.StatusBadRequest, "bad_request", "invalid "+name+": "+err.Error())
+				return
+			}
+		}
+	}
+
+	var tasks []*db.Task
+	if query := q.Get("q"); query != "" {
+		for _, res := range a.s.Search(query) {
+			if res.Archived == archived {
+				tasks = append(tasks, res.Task)
+			}
+		}
+	} else {
+		if archived {
+			tasks = a.s.ListArchivedTasks()
+		} else {
+			tasks = a.s.ListTasks()
+		}
+		slices.SortFunc(tasks, db.CompareSeq)
+	}
+
+	tag, list := q.Get("tag"), q.Get("list")
+	short := a.s.ShortIDs()
+	results := []apiTask{}
+	for _, t := range tasks {
+		if done != nil && t.Done != *done {
+			continue
+		}
+		if tag != "" && !t.HasTag(tag) {
+			continue
+		}
+		if list != "" && t.List != list {
+			continue
+		}
+		if !dueBefore.IsZero() && (t.Time.IsZero() || !t.Time.Before(dueBefore)) {
+			continue
+		}
+		if !dueAfter.IsZero() && !t.Time.After(dueAfter) {
+			continue
+		}
+		results = append(results, view(short, t, archived))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// changeFailed responds to a failed change of the id task. Only active tasks
+// change, so an archived or missing one explains the failure.
+func (a *taskAPI) changeFailed(w http.ResponseWriter, id string, err error) {
+	if _, ok := a.s.GetArchivedTask(id); ok {
+		writeError(w, http.StatusConflict, "archived", "archived tasks can't be changed, restore it first")
+	} else if _, ok := a.s.GetTask(id); !ok {
+		writeError(w, http.StatusNotFound, "not_found", "task not exists")
+	} else {
+		writeError(w, http.StatusConflict, "conflict", err.Error())
+	}
+}
+
+func queryBool(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+func (a *taskAPI) get(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.resolve(w, r)
+	if !ok {
+		return
+	}
+
+	if t, ok := a.s.GetTask(id); ok {
+		writeJSON(w, http.StatusOK, view(a.s.ShortIDs(), t, false))
+	} else if t, ok := a.s.GetArchivedTask(id); ok {
+		writeJSON(w, http.StatusOK, view(a.s.ShortIDs(), t, true))
+	} else {
+		writeError(w, http.StatusNotFound, "not_found", "task not exists")
+	}
+}
+
+func decodeInput(w http.ResponseWriter, r *http.Request) (*taskInput, bool) {
+	defer r.Body.Close()
+
+	var in taskInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid body: "+err.Error())
+		return nil, false
+	}
+	return &in, true
+}
+
+func (in *taskInput) apply(t *db.Task) {
+	if in.Name != nil {
+		t.Name = *in.Name
+	}
+	if in.Description != nil {
+		t.Description = *in.Description
+	}
+	if in.Time != nil {
+		t.Time = in.Time.Time
+	}
+	if in.Tags != nil {
+		t.Tags = *in.Tags
+	}
+	if in.List != nil {
+		t.List = *in.List
+	}
+}
+
+func (a *taskAPI) create(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeInput(w, r)
+	if !ok {
+		return
+	}
+
+	t := db.NewTaskBuilder(db.UuidIdGenerator).Build()
+	in.apply(t)
+	if t.Name == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "name is required")
+		return
+	}
+
+	stored, err := applyAdd(a.s, t, hooks.SourceAPI)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "rejected", err.Error())
+		return
+	}
+	if !a.save(w) {
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, view(a.s.ShortIDs(), stored, false))
+}
+
+func (a *taskAPI) replace(w http.ResponseWriter, r *http.Request) {
+	a.update(w, r, false)
+}
+
+func (a *taskAPI) patch(w http.ResponseWriter, r *http.Request) {
+	a.update(w, r, true)
+}
+
+var errNameRequired = errors.New("name is required")
+
+// update edits name, description, due time, tags and list. PUT replaces all
+// of them, PATCH only the fields present in the body.
+func (a *taskAPI) update(w http.ResponseWriter, r *http.Request, partial bool) {
+	id, ok := a.resolve(w, r)
+	if !ok {
+		return
+	}
+	in, ok := decodeInput(w, r)
+	if !ok {
+		return
+	}
+
+	var t *db.Task
+	err := a.s.UpdateTaskFunc(id, func(stored *db.Task) error {
+		if !partial {
+			stored.Name, stored.Description, stored.Time, stored.Tags, stored.List = "", "", time.Time{}, nil, ""
+		}
+		in.apply(stored)
+		if stored.Name == "" {
+			return errNameRequired
+		}
+		t = stored
+		return nil
+	})
+	if errors.Is(err, errNameRequired) {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if err != nil {
+		a.changeFailed(w, id, err)
+		return
+	}
+	if !a.save(w) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, view(a.s.ShortIDs(), t, false))
+}
+
+func (a *taskAPI) markDone(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.resolve(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := applyMarkDone(a.s, id, hooks.SourceAPI)
+	if err != nil {
+		a.changeFailed(w, id, err)
+		return
+	}
+	if !a.save(w) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, view(a.s.ShortIDs(), t, false))
+}
+
+func (a *taskAPI) delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.resolve(w, r)
+	if !ok {
+		return
+	}
+
+	if err := applyDelete(a.s, id, hooks.SourceAPI); err != nil {
+		a.changeFailed(w, id, err)
+		return
+	}
+	if !a.save(w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+type apiTaskResponse struct {
+	ID       string    `json:"id"`
+	ShortID  string    `json:"short_id"`
+	Seq      int       `json:"seq"`
+	Name     string    `json:"name"`
+	Desc     string    `json:"desc"`
+	Time     time.Time `json:"time"`
+	Done     bool      `json:"done"`
+	Archived bool      `json:"archived"`
+}
+
+func setupTestAPI(t *testing.T) (*httptest.Server, *db.Storage) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+	server := httptest.NewServer(apiMux(s))
+	t.Cleanup(server.Close)
+	return server, s
+}
+
+func apiRequest(t *testing.T, method, url, body string, target any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if target != nil {
+		if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+			t.Fatalf("Failed to decode %s %s response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAPI_TaskLifecycle(t *testing.T) {
+	server, s := setupTestAPI(t)
+
+	var created apiTaskResponse
+	status := apiRequest(t, http.MethodPost, server.URL+"/tasks", `{"name": "Send invoice", "desc": "client", "time": "2025-04-18T10:30:00Z"}`, &created)
+	if status != http.StatusCreated || created.Seq != 1 || created.ShortID == "" {
+		t.Fatalf("unexpected create response %d %+v", status, created)
+	}
+	if _, ok := s.GetTask(created.ID); !ok {
+		t.Errorf("created task not in shared storage")
+	}
+	if _, ok := db.GetStorage().GetTask(created.ID); !ok {
+		t.Errorf("created task must be saved to disk")
+	}
+
+	var fetched apiTaskResponse
+	if status := apiRequest(t, http.MethodGet, server.URL+"/tasks/1", "", &fetched); status != http.StatusOK || fetched.ID != created.ID {
+		t.Errorf("get by number failed: %d %+v", status, fetched)
+	}
+
+	var patched apiTaskResponse
+	apiRequest(t, http.MethodPatch, server.URL+"/tasks/"+created.ShortID, `{"desc": "client ACME"}`, &patched)
+	if patched.Name != "Send invoice" || patched.Desc != "client ACME" || !patched.Time.Equal(created.Time) {
+		t.Errorf("PATCH must change only given fields, got %+v", patched)
+	}
+
+	var replaced apiTaskResponse
+	apiRequest(t, http.MethodPut, server.URL+"/tasks/"+created.ID, `{"name": "Send invoices"}`, &replaced)
+	if replaced.Name != "Send invoices" || replaced.Desc != "" || !replaced.Time.IsZero() || replaced.Seq != 1 {
+		t.Errorf("PUT must replace editable fields, got %+v", replaced)
+	}
+
+	var done apiTaskResponse
+	if status := apiRequest(t, http.MethodPost, server.URL+"/tasks/1/done", "", &done); status != http.StatusOK || !done.Done {
+		t.Errorf("mark done failed: %d %+v", status, done)
+	}
+	if status := apiRequest(t, http.MethodPost, server.URL+"/tasks/1/done", "", nil); status != http.StatusConflict {
+		t.Errorf("expected conflict marking done twice, got %d", status)
+	}
+
+	if status := apiRequest(t, http.MethodDelete, server.URL+"/tasks/1", "", nil); status != http.StatusNoContent {
+		t.Errorf("expected 204 on delete, got %d", status)
+	}
+	if status := apiRequest(t, http.MethodGet, server.URL+"/tasks/"+created.ID, "", nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", status)
+	}
+}
+
+func TestAPI_List(t *testing.T) {
+	server, s := setupTestAPI(t)
+
+	for _, body := range []string{
+		`{"name": "Send invoice", "time": "2025-04-18T10:00:00Z"}`,
+		`{"name": "Pay rent", "time": "2025-05-01T10:00:00Z"}`,
+		`{"name": "Invoice review"}`,
+	} {
+		if status := apiRequest(t, http.MethodPost, server.URL+"/tasks", body, nil); status != http.StatusCreated {
+			t.Fatalf("create failed with %d", status)
+		}
+	}
+	apiRequest(t, http.MethodPost, server.URL+"/tasks/2/done", "", nil)
+
+	names := func(query string) string {
+		var list struct {
+			Results []apiTaskResponse `json:"results"`
+			Count   int               `json:"count"`
+		}
+		if status := apiRequest(t, http.MethodGet, server.URL+"/tasks"+query, "", &list); status != http.StatusOK {
+			t.Fatalf("list %q failed with %d", query, status)
+		}
+		result := []string{}
+		for _, r := range list.Results {
+			result = append(result, r.Name)
+		}
+		if list.Count != len(result) {
+			t.Errorf("count %d doesn't match results %v", list.Count, result)
+		}
+		return strings.Join(result, ",")
+	}
+
+	var tests = []struct {
+		query    string
+		expected string
+	}{
+		{"", "Send invoice,Pay rent,Invoice review"},
+		{"?done=false", "Send invoice,Invoice review"},
+		{"?done=true", "Pay rent"},
+		{"?q=invoice", "Send invoice,Invoice review"},
+		{"?due_before=2025-04-20", "Send invoice"},
+		{"?due_after=2025-04-20", "Pay rent"},
+		{"?archived=true", ""},
+	}
+	for _, tt := range tests {
+		if got := names(tt.query); got != tt.expected {
+			t.Errorf("list %q: expected %q, got %q", tt.query, tt.expected, got)
+		}
+	}
+
+	s.ArchiveDone(-time.Hour)
+	if got := names("?archived=true"); got != "Pay rent" {
+		t.Errorf("expected archived task, got %q", got)
+	}
+}
+
+func TestAPI_Errors(t *testing.T) {
+	server, _ := setupTestAPI(t)
+
+	var tests = []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodPost, "/tasks", `not json`, http.StatusBadRequest},
+		{http.MethodPost, "/tasks", `{"desc": "no name"}`, http.StatusBadRequest},
+		{http.MethodPost, "/tasks", `{"name": "x", "time": "03/04"}`, http.StatusBadRequest},
+		{http.MethodGet, "/tasks?done=maybe", ``, http.StatusBadRequest},
+		{http.MethodGet, "/tasks/42", ``, http.StatusNotFound},
+		{http.MethodPatch, "/tasks/42", `{}`, http.StatusNotFound},
+		{http.MethodDelete, "/tasks/42", ``, http.StatusNotFound},
+		{http.MethodPut, "/tasks", `{}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		if status := apiRequest(t, tt.method, server.URL+tt.path, tt.body, nil); status != tt.expected {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.expected, status)
+		}
+	}
+}
+
+func TestAPI_ArchivedTask(t *testing.T) {
+	server, s := setupTestAPI(t)
+
+	var created apiTaskResponse
+	apiRequest(t, http.MethodPost, server.URL+"/tasks", `{"name": "Filed"}`, &created)
+	apiRequest(t, http.MethodPost, server.URL+"/tasks/1/done", "", nil)
+	s.ArchiveDone(-time.Hour)
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/tasks/1/done", ""},
+		{http.MethodDelete, "/tasks/1", ""},
+		{http.MethodPatch, "/tasks/1", `{"name": "Unfiled"}`},
+	} {
+		var body map[string]string
+		status := apiRequest(t, req.method, server.URL+req.path, req.body, &body)
+		if status != http.StatusConflict || body["error"] != "archived" {
+			t.Errorf("%s %s: expected 409 archived, got %d %v", req.method, req.path, status, body)
+		}
+	}
+}
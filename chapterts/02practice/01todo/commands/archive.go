@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+	"todo/cli/db"
+)
+
+const defaultArchiveAge = 7 * 24 * time.Hour
+
+func listCmd(fs *flag.FlagSet) func(args []string) {
+	archived := fs.Bool("archived", false, "list archived tasks")
+
+	return func(args []string) {
+		if *archived {
+			logger.Info("archived task", "tasks", listArchivedTasks())
+		} else {
+			logger.Info("task", "tasks", listTasks())
+		}
+	}
+}
+
+func archiveCmd(fs *flag.FlagSet) func(args []string) {
+	olderThan := fs.Duration("older-than", defaultArchiveAge, "archive tasks done longer ago than this")
+
+	return func(args []string) {
+		if ids, err := archiveTasks(*olderThan); err == nil {
+			logger.Info("tasks archived", "ids", ids)
+		} else {
+			logger.Warn("task archive error", "error", err)
+		}
+	}
+}
+
+func restoreCmd(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) != 1 {
+			logger.Warn("invalid restore call", "args", args, "expected", "restore <id>")
+			return
+		}
+		if err := restoreTask(args[0]); err == nil {
+			logger.Info("task restored", "id", args[0])
+		} else {
+			logger.Warn("task restore error", "error", err)
+		}
+	}
+}
+
+func exportCmd(fs *flag.FlagSet) func(args []string) {
+	archived := fs.Bool("archived", false, "export archived tasks only")
+	all := fs.Bool("all", false, "export active and archived tasks")
+
+	return func(args []string) {
+		s := db.GetStorage()
+
+		var tasks []*db.Task
+		switch {
+		case *all:
+			tasks = append(s.ListTasks(), s.ListArchivedTasks()...)
+		case *archived:
+			tasks = s.ListArchivedTasks()
+		default:
+			tasks = s.ListTasks()
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(tasks); err != nil {
+			logger.Warn("task export error", "error", err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"todo/cli/board"
+	"todo/cli/db"
+	"todo/cli/hooks"
+)
+
+const (
+	boardByStatus = "status"
+	boardByTag    = "tag"
+
+	todoColumn     = "Todo"
+	doneColumn     = "Done"
+	untaggedColumn = "Untagged"
+)
+
+func boardCmd(fs *flag.FlagSet) func(args []string) {
+	fp := fs.String("file", "./BOARD.md", "markdown file the board is written to")
+	by := fs.String("by", boardByStatus, "group tasks by \"status\" or \"tag\"")
+	watch := fs.Bool("watch", false, "keep the file up to date and apply edits made to it")
+	interval := fs.Duration("interval", 2*time.Second, "how often watch mode checks for changes")
+	applySettings := settingsFlags(fs)
+
+	return func(args []string) {
+		if err := applySettings(); err != nil {
+			logger.Warn("invalid settings", "error", err)
+			return
+		}
+		if *by != boardByStatus && *by != boardByTag {
+			logger.Warn("invalid board grouping", "by", *by, "expected", "status or tag")
+			return
+		}
+
+		b := &boardSync{fp: *fp, by: *by}
+		b.load()
+		if err := b.write(); err != nil {
+			logger.Warn("board write error", "error", err)
+			return
+		}
+		logger.Info("board written", "file", *fp)
+		if !*watch {
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+		defer stop()
+
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := b.sync(); err != nil {
+				logger.Warn("board sync error", "error", err)
+			}
+		}
+	}
+}
+
+// boardSync keeps a board file and the storage in step. written is the
+// content last written to the file, any other content are edits to apply.
+type boardSync struct {
+	fp       string
+	by       string
+	s        *db.Storage
+	storeMod time.Time
+	written  string
+}
+
+func (b *boardSync) load() {
+	b.s = db.GetStorage()
+	b.storeMod = db.StorageModTime()
+}
+
+func (b *boardSync) write() error {
+	var buf bytes.Buffer
+	if err := board.Render(&buf, buildBoard(b.s.ListTasks(), b.by)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(b.fp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	b.written = buf.String()
+	return nil
+}
+
+// sync reloads tasks saved by other processes, applies edits of the file
+// and rewrites it when anything changed.
+func (b *boardSync) sync() error {
+	changed := false
+	if db.StorageModTime().After(b.storeMod) {
+		b.load()
+		changed = true
+	}
+
+	data, err := os.ReadFile(b.fp)
+	switch {
+	case os.IsNotExist(err):
+		changed = true
+	case err != nil:
+		return err
+	case string(data) != b.written:
+		parsed, err := board.Parse(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if n := applyBoard(b.s, parsed, b.by); n > 0 {
+			logger.Info("board changes applied", "counter", n)
+			if err := b.s.Save(); err != nil {
+				return err
+			}
+			b.storeMod = db.StorageModTime()
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return b.write()
+}
+
+func buildBoard(tasks []*db.Task, by string) board.Board {
+	slices.SortFunc(tasks, db.CompareSeq)
+
+	b := board.Board{Title: "Tasks"}
+	if by == boardByStatus {
+		b.Columns = []board.Column{{Name: todoColumn}, {Name: doneColumn}}
+	}
+	for _, t := range tasks {
+		name := boardColumn(t, by)
+		c := b.Column(name)
+		if c == nil {
+			b.Columns = append(b.Columns, board.Column{Name: name})
+			c = &b.Columns[len(b.Columns)-1]
+		}
+		c.Items = append(c.Items, board.Item{ID: t.ID.String(), Done: t.Done, Text: t.Name, Note: boardNote(t, by)})
+	}
+
+	if by == boardByTag {
+		sort.SliceStable(b.Columns, func(i, j int) bool {
+			if (b.Columns[i].Name == untaggedColumn) != (b.Columns[j].Name == untaggedColumn) {
+				return b.Columns[j].Name == untaggedColumn
+			}
+			return strings.ToLower(b.Columns[i].Name) < strings.ToLower(b.Columns[j].Name)
+		})
+	}
+	return b
+}
+
+// boardColumn is the column t is shown in: its status, or its first tag.
+func boardColumn(t *db.Task, by string) string {
+	switch {
+	case by == boardByStatus && t.Done:
+		return doneColumn
+	case by == boardByStatus:
+		return todoColumn
+	case len(t.Tags) == 0:
+		return untaggedColumn
+	}
+	return t.Tags[0]
+}
+
+func boardNote(t *db.Task, by string) string {
+	parts := []string{}
+	if !t.Time.IsZero() {
+		parts = append(parts, "due "+t.Time.In(dueParser.Location()).Format("2006-01-02 15:04"))
+	}
+	if by == boardByStatus {
+		for _, tag := range t.Tags {
+			parts = append(parts, "#"+tag)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// applyBoard turns differences between the parsed board and the stored tasks
+// into task operations and returns how many tasks changed. Lines removed from
+// the file don't delete tasks.
+func applyBoard(s *db.Storage, b board.Board, by string) int {
+	changes := 0
+	seen := map[string]bool{}
+
+	for _, c := range b.Columns {
+		for _, it := range c.Items {
+			var err error
+			changed := false
+			if it.ID == "" {
+				changed, err = addBoardItem(s, c.Name, it, by)
+			} else if id, rerr := s.ResolveID(it.ID); rerr != nil {
+				err = rerr
+			} else if !seen[id] {
+				seen[id] = true
+				changed, err = updateBoardItem(s, id, c.Name, it, by)
+			}
+
+			if err != nil {
+				logger.Warn("board line skipped", "column", c.Name, "text", it.Text, "error", err)
+			} else if changed {
+				changes++
+			}
+		}
+	}
+	return changes
+}
+
+func addBoardItem(s *db.Storage, column string, it board.Item, by string) (bool, error) {
+	builder := db.NewTaskBuilder(db.UuidIdGenerator).WithName(it.Text)
+	if by == boardByTag && !strings.EqualFold(column, untaggedColumn) {
+		builder.WithTags(column)
+	}
+	t := builder.Build()
+
+	if _, err := applyAdd(s, t, hooks.SourceBoard); err != nil {
+		return false, err
+	}
+	if wantDone(by, column, it, false) {
+		if _, err := applyMarkDone(s, t.ID.String(), hooks.SourceBoard); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func updateBoardItem(s *db.Storage, id, column string, it board.Item, by string) (bool, error) {
+	t, ok := s.GetTask(id)
+	if !ok {
+		return false, fmt.Errorf("task %v is not active", id)
+	}
+
+	done := wantDone(by, column, it, t.Done)
+	edited := false
+	if it.Text != "" && it.Text != t.Name {
+		t.Name = it.Text
+		edited = true
+	}
+	if by == boardByTag && !strings.EqualFold(boardColumn(t, by), column) {
+		t.Tags = moveTag(t.Tags, column)
+		edited = true
+	}
+	if t.Done && !done {
+		t.Done, t.DoneAt = false, nil
+		edited = true
+	}
+
+	if edited {
+		if err := s.UpdateTask(t); err != nil {
+			return false, err
+		}
+	}
+	if done && !t.Done {
+		if _, err := applyMarkDone(s, id, hooks.SourceBoard); err != nil {
+			return edited, err
+		}
+		return true, nil
+	}
+	return edited, nil
+}
+
+// wantDone decides the status of a line. A toggled checkbox wins, otherwise
+// the Todo and Done columns of a status board set it.
+func wantDone(by, column string, it board.Item, stored bool) bool {
+	if it.Done != stored || by != boardByStatus {
+		return it.Done
+	}
+	switch {
+	case strings.EqualFold(column, doneColumn):
+		return true
+	case strings.EqualFold(column, todoColumn):
+		return false
+	}
+	return stored
+}
+
+// moveTag replaces the first tag, which chose the old column, by column.
+// Moving to the Untagged column drops all tags.
+func moveTag(tags []string, column string) []string {
+	if strings.EqualFold(column, untaggedColumn) {
+		return nil
+	}
+
+	moved := []string{column}
+	for i, tag := range tags {
+		if i > 0 && !strings.EqualFold(tag, column) {
+			moved = append(moved, tag)
+		}
+	}
+	return moved
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+func setupBoard(t *testing.T, by string, tasks ...*db.Task) *boardSync {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+	for _, task := range tasks {
+		if err := s.AddTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &boardSync{fp: "BOARD.md", by: by}
+	b.load()
+	if err := b.write(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func editBoard(t *testing.T, b *boardSync, edit func(string) string) {
+	data, err := os.ReadFile(b.fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b.fp, []byte(edit(string(data))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBoard_StatusSync(t *testing.T) {
+	invoice := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Send invoice").Build()
+	rent := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Pay rent").WithTags("home").Build()
+	milk := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Buy milk").Build()
+	b := setupBoard(t, boardByStatus, invoice, rent, milk)
+	b.s.MarkDone(milk.ID.String())
+	b.write()
+
+	expected := "# Tasks\n\n## Todo\n\n" +
+		"- [ ] Send invoice <!-- id:" + invoice.ID.String() + " -->\n" +
+		"- [ ] Pay rent _(#home)_ <!-- id:" + rent.ID.String() + " -->\n" +
+		"\n## Done\n\n" +
+		"- [x] Buy milk <!-- id:" + milk.ID.String() + " -->\n"
+	if b.written != expected {
+		t.Fatalf("unexpected board:\n%s\nexpected:\n%s", b.written, expected)
+	}
+
+	editBoard(t, b, func(s string) string {
+		lines := strings.Split(s, "\n")
+		// check invoice, move rent below Done, reopen milk, add a new line
+		lines[4] = strings.Replace(lines[4], "[ ]", "[x]", 1)
+		rentLine := lines[5]
+		lines[5] = "- [ ] Call the bank"
+		lines[9] = strings.Replace(lines[9], "[x] Buy milk", "[ ] Buy oat milk", 1)
+		return strings.Join(append(lines[:10], rentLine, ""), "\n")
+	})
+
+	s := db.GetStorage()
+	for id, done := range map[string]bool{invoice.ID.String(): true, rent.ID.String(): true, milk.ID.String(): false} {
+		if task, _ := s.GetTask(id); task.Done != done {
+			t.Errorf("task %q: expected done %v", task.Name, done)
+		}
+	}
+	if task, _ := s.GetTask(milk.ID.String()); task.Name != "Buy oat milk" || task.DoneAt != nil {
+		t.Errorf("expected reopened and renamed task, got %+v", task)
+	}
+	if len(s.ListTasks()) != 4 {
+		t.Errorf("expected new task from the board, got %d tasks", len(s.ListTasks()))
+	}
+
+	if !strings.Contains(b.written, "## Todo\n\n- [ ] Buy oat milk <!-- id:"+milk.ID.String()+" -->\n- [ ] Call the bank <!-- id:") {
+		t.Errorf("board must be rewritten with the new task id:\n%s", b.written)
+	}
+}
+
+func TestBoard_TagSync(t *testing.T) {
+	invoice := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Send invoice").WithTags("work", "money").WithTime(time.Date(2025, 4, 18, 10, 30, 0, 0, time.Local)).Build()
+	rent := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Pay rent").WithTags("home").Build()
+	milk := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Buy milk").Build()
+	b := setupBoard(t, boardByTag, invoice, rent, milk)
+
+	expected := "# Tasks\n\n## home\n\n" +
+		"- [ ] Pay rent <!-- id:" + rent.ID.String() + " -->\n" +
+		"\n## work\n\n" +
+		"- [ ] Send invoice _(due 2025-04-18 10:30)_ <!-- id:" + invoice.ID.String() + " -->\n" +
+		"\n## Untagged\n\n" +
+		"- [ ] Buy milk <!-- id:" + milk.ID.String() + " -->\n"
+	if b.written != expected {
+		t.Fatalf("unexpected board:\n%s\nexpected:\n%s", b.written, expected)
+	}
+
+	editBoard(t, b, func(s string) string {
+		lines := strings.Split(s, "\n")
+		// move invoice to home, milk to work, add a new line to home
+		invoiceLine, milkLine := lines[8], lines[12]
+		lines[8], lines[12] = milkLine, "- [x] Water plants"
+		return strings.Join(append(lines[:5], append([]string{invoiceLine}, lines[5:]...)...), "\n")
+	})
+
+	s := db.GetStorage()
+	var tests = []struct {
+		id   string
+		tags []string
+	}{
+		{invoice.ID.String(), []string{"home", "money"}},
+		{milk.ID.String(), []string{"work"}},
+		{rent.ID.String(), []string{"home"}},
+	}
+	for _, tt := range tests {
+		if task, _ := s.GetTask(tt.id); !reflect.DeepEqual(task.Tags, tt.tags) {
+			t.Errorf("task %q: expected tags %v, got %v", task.Name, tt.tags, task.Tags)
+		}
+	}
+
+	var plants *db.Task
+	for _, task := range s.ListTasks() {
+		if task.Name == "Water plants" {
+			plants = task
+		}
+	}
+	if plants == nil || plants.Tags != nil || !plants.Done {
+		t.Errorf("expected done untagged task from the board, got %+v", plants)
+	}
+}
+
+func TestBoard_ReloadsStorage(t *testing.T) {
+	b := setupBoard(t, boardByStatus)
+
+	other := db.GetStorage()
+	other.AddTask(db.NewTaskBuilder(db.UuidIdGenerator).WithName("From daemon").Build())
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes("storage.json", time.Now().Add(time.Second), time.Now().Add(time.Second))
+
+	if err := b.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.written, "From daemon") {
+		t.Errorf("board must show tasks saved by other processes:\n%s", b.written)
+	}
+}
@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"todo/cli/db"
+)
+
+const completeCmdName = "__complete"
+
+func completionCmd(fs *flag.FlagSet) func(args []string) {
+	prog := fs.String("prog", "todo", "name of the installed binary")
+
+	return func(args []string) {
+		if len(args) != 1 {
+			logger.Warn("invalid completion call", "args", args, "expected", "completion bash|zsh|fish")
+			return
+		}
+		if err := writeCompletionScript(os.Stdout, args[0], *prog); err != nil {
+			logger.Warn("completion error", "error", err)
+		}
+	}
+}
+
+// completeCmd prints candidates for the last of args, the word being
+// completed, as "value\tdescription" lines. Scripts pass the words after
+// "--" so they aren't parsed as flags of the command itself.
+func completeCmd(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		for _, c := range complete(args, db.GetStorage) {
+			if c.desc == "" {
+				fmt.Println(c.value)
+			} else {
+				fmt.Printf("%s\t%s\n", c.value, strings.ReplaceAll(c.desc, "\n", " "))
+			}
+		}
+	}
+}
+
+type candidate struct {
+	value string
+	desc  string
+}
+
+// complete returns candidates for the last word of the command line words
+// following the program name. load is only called for task ids and tags.
+func complete(words []string, load func() *db.Storage) []candidate {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+
+	name := ""
+	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	if len(prev) > 0 {
+		if sc, ok := subcommands[prev[0]]; ok {
+			name = prev[0]
+			prev = prev[1:]
+			sc.setup(fs)
+		}
+	}
+	if name == "" {
+		rootFlags(fs)
+		daemonFlags(fs)
+		settingsFlags(fs)
+	}
+
+	if len(prev) > 0 {
+		if f := fs.Lookup(strings.TrimLeft(prev[len(prev)-1], "-")); f != nil && strings.HasPrefix(prev[len(prev)-1], "-") && !isBoolFlag(f) {
+			return filterPrefix(flagValues(f.Name, cur, load), cur)
+		}
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		if flagName, value, ok := strings.Cut(strings.TrimLeft(cur, "-"), "="); ok {
+			dashes := cur[:len(cur)-len(strings.TrimLeft(cur, "-"))]
+			result := []candidate{}
+			for _, c := range filterPrefix(flagValues(flagName, value, load), value) {
+				result = append(result, candidate{dashes + flagName + "=" + c.value, c.desc})
+			}
+			return result
+		}
+		return filterPrefix(flagCandidates(fs), cur)
+	}
+
+	switch {
+	case name == "" && len(prev) == 0:
+		return filterPrefix(subcommandCandidates(), cur)
+	case name == "restore":
+		return filterPrefix(taskCandidates(load(), true), cur)
+	}
+	return nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagValues returns candidates for the value of a flag, none for values
+// like paths the shell completes better itself.
+func flagValues(name, cur string, load func() *db.Storage) []candidate {
+	switch name {
+	case "get", "del", "done":
+		return taskCandidates(load(), false)
+	case "tags":
+		return tagCandidates(load(), cur)
+	case "by":
+		return []candidate{{boardByStatus, "columns Todo and Done"}, {boardByTag, "one column per tag"}}
+	}
+	return nil
+}
+
+func subcommandCandidates() []candidate {
+	result := []candidate{}
+	for name, sc := range subcommands {
+		result = append(result, candidate{name, sc.usage})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].value < result[j].value })
+	return result
+}
+
+func flagCandidates(fs *flag.FlagSet) []candidate {
+	result := []candidate{}
+	fs.VisitAll(func(f *flag.Flag) {
+		result = append(result, candidate{"-" + f.Name, f.Usage})
+	})
+	return result
+}
+
+func taskCandidates(s *db.Storage, archived bool) []candidate {
+	tasks := s.ListTasks()
+	if archived {
+		tasks = s.ListArchivedTasks()
+	}
+
+	result := []candidate{}
+	for _, v := range taskViews(s, tasks) {
+		result = append(result, candidate{v.ShortID, v.Name})
+	}
+	return result
+}
+
+// tagCandidates completes the last tag of a comma separated list.
+func tagCandidates(s *db.Storage, cur string) []candidate {
+	done := ""
+	if i := strings.LastIndex(cur, ","); i >= 0 {
+		done = cur[:i+1]
+	}
+
+	counts := map[string]int{}
+	for _, t := range s.ListTasks() {
+		for _, tag := range t.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := []candidate{}
+	for tag, n := range counts {
+		result = append(result, candidate{done + tag, fmt.Sprintf("%d tasks", n)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].value < result[j].value })
+	return result
+}
+
+func filterPrefix(candidates []candidate, prefix string) []candidate {
+	result := []candidate{}
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c.value), strings.ToLower(prefix)) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func writeCompletionScript(w io.Writer, shell, prog string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)
+	}
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(prog)
+
+	_, err := io.WriteString(w, strings.NewReplacer("{{prog}}", prog, "{{fn}}", fn, "{{complete}}", completeCmdName+" --").Replace(script))
+	return err
+}
+
+// completionScripts call back into the binary for candidates. Without
+// candidates bash and zsh fall back to completing file names.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for {{prog}}, load with: source <({{prog}} completion bash)
+{{fn}}_complete() {
+    local IFS=$'\n' out line cur i
+    # COMP_WORDBREAKS splits "-tags=wo" into "-tags", "=", "wo", join them back
+    local -a words=()
+    local cword=-1
+    for ((i = 0; i <= COMP_CWORD; i++)); do
+        if ((i > 0)) && [[ ${COMP_WORDS[i]} == "=" || ${COMP_WORDS[i-1]} == "=" ]]; then
+            words[cword]+=${COMP_WORDS[i]}
+        else
+            words[++cword]=${COMP_WORDS[i]}
+        fi
+    done
+    cur=${words[cword]}
+    out=$({{prog}} {{complete}} "${words[@]:1}" 2>/dev/null | cut -f1)
+    COMPREPLY=()
+    if [[ -z $out ]]; then
+        compopt -o default
+        return
+    fi
+    for line in $out; do
+        # bash replaces only the part after "=", drop the flag from candidates
+        [[ $cur == *=* ]] && line=${line#"${cur%=*}="}
+        COMPREPLY+=("$line")
+    done
+}
+complete -F {{fn}}_complete {{prog}}
+`,
+	"zsh": `#compdef {{prog}}
+# zsh completion for {{prog}}, load with: source <({{prog}} completion zsh)
+{{fn}}() {
+    local -a candidates
+    local line value
+    for line in "${(@f)$({{prog}} {{complete}} "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        value=${line%%$'\t'*}
+        if [[ $line == *$'\t'* ]]; then
+            candidates+=("${value//:/\\:}:${line#*$'\t'}")
+        else
+            candidates+=("${value//:/\\:}")
+        fi
+    done
+    if (( ${#candidates} )); then
+        _describe '{{prog}}' candidates
+    else
+        _files
+    fi
+}
+compdef {{fn}} {{prog}}
+`,
+	"fish": `# fish completion for {{prog}}, load with: {{prog}} completion fish | source
+function __{{fn}}_complete
+    set -l tokens (commandline -opc)
+    {{prog}} {{complete}} $tokens[2..-1] (commandline -ct | string collect --allow-empty) 2>/dev/null
+end
+complete -c {{prog}} -f -a '(__{{fn}}_complete)'
+`,
+}
@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"todo/cli/db"
+)
+
+func TestComplete(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+	invoice := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Send invoice").WithTags("work", "money").Build()
+	rent := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Pay rent").WithTags("home").Build()
+	milk := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Buy milk").Build()
+	for _, task := range []*db.Task{invoice, rent, milk} {
+		s.AddTask(task)
+	}
+	s.MarkDone(milk.ID.String())
+	s.ArchiveDone(-1)
+	short := s.ShortIDs()
+
+	loads := 0
+	load := func() *db.Storage {
+		loads++
+		return s
+	}
+
+	values := func(words ...string) []string {
+		result := []string{}
+		for _, c := range complete(words, load) {
+			result = append(result, c.value)
+		}
+		return result
+	}
+
+	var tests = []struct {
+		words    []string
+		expected []string
+	}{
+		{[]string{"ar"}, []string{"archive"}},
+		{[]string{"-ti"}, []string{}},
+		{[]string{"-ta"}, []string{"-tags"}},
+		{[]string{"-get", ""}, []string{short[invoice.ID.String()], short[rent.ID.String()]}},
+		{[]string{"-list", "-done", short[rent.ID.String()]}, []string{short[rent.ID.String()]}},
+		{[]string{"-new", "x|y", "-tags", "work,h"}, []string{"work,home"}},
+		{[]string{"-tags="}, []string{"-tags=home", "-tags=money", "-tags=work"}},
+		{[]string{"restore", ""}, []string{short[milk.ID.String()]}},
+		{[]string{"board", "-by", ""}, []string{"status", "tag"}},
+		{[]string{"board", "--by=t"}, []string{"--by=tag"}},
+		{[]string{"serve", "-ad"}, []string{"-addr"}},
+		{[]string{"-config", ""}, []string{}},
+		{[]string{"list", ""}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := values(tt.words...); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("complete %q: expected %v, got %v", tt.words, tt.expected, got)
+		}
+	}
+
+	loads = 0
+	root := complete([]string{""}, load)
+	if loads != 0 {
+		t.Errorf("storage must not be loaded to complete subcommands")
+	}
+	for _, c := range root {
+		if c.value == completeCmdName {
+			t.Errorf("hidden command must not be offered")
+		}
+		if c.value == "search" && c.desc != subcommands["search"].usage {
+			t.Errorf("expected usage as description, got %q", c.desc)
+		}
+	}
+	if len(root) != len(subcommands) {
+		t.Errorf("expected every subcommand, got %d of %d", len(root), len(subcommands))
+	}
+
+	ids := complete([]string{"-get", ""}, load)
+	if ids[0].desc != "Send invoice" {
+		t.Errorf("expected task name as description, got %q", ids[0].desc)
+	}
+}
+
+func TestWriteCompletionScript(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var b bytes.Buffer
+		if err := writeCompletionScript(&b, shell, "my-todo"); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		script := b.String()
+		if !strings.Contains(script, "my-todo "+completeCmdName+" -- ") || strings.Contains(script, "{{") {
+			t.Errorf("%s: unexpected script:\n%s", shell, script)
+		}
+
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		fp := filepath.Join(t.TempDir(), "completion")
+		os.WriteFile(fp, b.Bytes(), 0o644)
+		if out, err := exec.Command(shell, "-n", fp).CombinedOutput(); err != nil {
+			t.Errorf("%s: invalid script: %v\n%s", shell, err, out)
+		}
+	}
+
+	if err := writeCompletionScript(&bytes.Buffer{}, "powershell", "todo"); err == nil {
+		t.Errorf("expected error for unsupported shell")
+	}
+}
+
+func TestBashCompletion_JoinsFlagValues(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	var b bytes.Buffer
+	writeCompletionScript(&b, "bash", "todo")
+
+	// the fake binary records its words and offers one candidate
+	args := filepath.Join(t.TempDir(), "args")
+	script := b.String() + `
+todo() { printf '%s\n' "$*" > ` + args + `; printf -- '-tags=work\thome tasks\n'; }
+COMP_WORDS=(todo -tags = wo)
+COMP_CWORD=3
+_todo_complete
+printf '%s\n' "${COMPREPLY[@]}"
+`
+	out, err := exec.Command("bash", "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, out)
+	}
+	if string(out) != "work\n" {
+		t.Errorf("expected value without the flag, got:\n%s", out)
+	}
+	if got, _ := os.ReadFile(args); string(got) != completeCmdName+"\n--\n-tags=wo\n" {
+		t.Errorf("expected joined flag word, got %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// fileConfig is the daemon configuration file. Every field is optional and
+// only used when the matching flag isn't given on the command line.
+type fileConfig struct {
+	Daemon        *string       `json:"daemon"`
+	ArchiveAfter  *duration     `json:"archive_after"`
+	Remind        []duration    `json:"remind"`
+	RemindWebhook *string       `json:"remind_webhook"`
+	RemindOutbox  *string       `json:"remind_outbox"`
+	RemindState   *string       `json:"remind_state"`
+	HealthMaxAge  *duration     `json:"health_max_age"`
+	Inboxes       []inboxConfig `json:"inboxes"`
+	NotesURL      *string       `json:"notes_url"`
+	NotesToken    *string       `json:"notes_token"`
+	NotesTokenEnv *string       `json:"notes_token_env"`
+	NotesAuthor   *string       `json:"notes_author"`
+	NotesMapping  *string       `json:"notes_mapping"`
+}
+
+// inboxConfig describes one watched dir in addition to -daemon.
+type inboxConfig struct {
+	Dir              string    `json:"dir"`
+	List             string    `json:"list"`
+	Ops              []string  `json:"ops"`
+	Interval         *duration `json:"interval"`
+	RequireSignature bool      `json:"require_signature"`
+	Secret           string    `json:"secret"`
+	SecretEnv        string    `json:"secret_env"`
+}
+
+func (ic inboxConfig) inbox() (inbox, error) {
+	if ic.Dir == "" {
+		return inbox{}, fmt.Errorf("inbox without dir")
+	}
+	in := defaultInbox(ic.Dir)
+	in.list = ic.List
+
+	if ic.Ops != nil {
+		in.ops = map[string]bool{}
+		for _, op := range ic.Ops {
+			if !allowedOp[op] {
+				return inbox{}, fmt.Errorf("inbox %v: unknown operation %q", ic.Dir, op)
+			}
+			in.ops[op] = true
+		}
+	}
+	if ic.Interval != nil {
+		if ic.Interval.Duration <= 0 {
+			return inbox{}, fmt.Errorf("inbox %v: interval must be positive", ic.Dir)
+		}
+		in.interval = ic.Interval.Duration
+	}
+
+	secret := ic.Secret
+	if ic.SecretEnv != "" {
+		secret = os.Getenv(ic.SecretEnv)
+	}
+	if ic.RequireSignature && secret == "" {
+		return inbox{}, fmt.Errorf("inbox %v: signature required but no secret set", ic.Dir)
+	}
+	in.requireSig = ic.RequireSignature
+	in.secret = []byte(secret)
+
+	return in, nil
+}
+
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func loadConfigFile(fp string) (fileConfig, error) {
+	var fc fileConfig
+
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return fc, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return fc, fmt.Errorf("invalid config %v: %w", fp, err)
+	}
+	return fc, nil
+}
+
+// apply copies values from the file to cfg unless their flag is in set.
+func (fc fileConfig) apply(cfg *daemonConfig, set map[string]bool) error {
+	if fc.Daemon != nil && !set["daemon"] {
+		cfg.src = *fc.Daemon
+	}
+	if fc.ArchiveAfter != nil && !set["archive-after"] {
+		cfg.archiveAfter = fc.ArchiveAfter.Duration
+	}
+	if fc.Remind != nil && !set["remind"] {
+		cfg.remind = make([]time.Duration, len(fc.Remind))
+		for i, d := range fc.Remind {
+			cfg.remind[i] = d.Duration
+		}
+	}
+	if fc.RemindWebhook != nil && !set["remind-webhook"] {
+		cfg.remindWebhook = *fc.RemindWebhook
+	}
+	if fc.RemindOutbox != nil && !set["remind-outbox"] {
+		cfg.remindOutbox = *fc.RemindOutbox
+	}
+	if fc.RemindState != nil && !set["remind-state"] {
+		cfg.remindState = *fc.RemindState
+	}
+	if fc.HealthMaxAge != nil && !set["health-max-age"] {
+		cfg.healthMaxAge = fc.HealthMaxAge.Duration
+	}
+
+	if fc.NotesURL != nil && !set["notes-url"] {
+		cfg.notes.url = *fc.NotesURL
+	}
+	if !set["notes-token"] {
+		switch {
+		case fc.NotesToken != nil:
+			cfg.notes.token = *fc.NotesToken
+		case fc.NotesTokenEnv != nil:
+			cfg.notes.token = os.Getenv(*fc.NotesTokenEnv)
+		}
+	}
+	if fc.NotesAuthor != nil && !set["notes-author"] {
+		cfg.notes.author = *fc.NotesAuthor
+	}
+	if fc.NotesMapping != nil && !set["notes-mapping"] {
+		cfg.notes.mapping = *fc.NotesMapping
+	}
+
+	for _, ic := range fc.Inboxes {
+		in, err := ic.inbox()
+		if err != nil {
+			return err
+		}
+		cfg.inboxes = append(cfg.inboxes, in)
+	}
+	return nil
+}
+
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
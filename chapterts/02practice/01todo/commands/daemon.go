@@ -1,70 +1,267 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 	"todo/cli/db"
+	"todo/cli/hooks"
+	"todo/cli/reminders"
 
 	"github.com/google/uuid"
 )
 
-func daemon(src string) {
-	wd, _ := os.Getwd()
-	logger.Info("Daemon started", "wd", wd, "src", src)
+type daemonConfig struct {
+	src           string
+	archiveAfter  time.Duration
+	remind        []time.Duration
+	remindWebhook string
+	remindOutbox  string
+	remindState   string
+	metricsAddr   string
+	healthMaxAge  time.Duration
+	inboxes       []inbox
+	notes         notesConfig
+}
+
+// allInboxes returns configured inboxes together with the -daemon dir.
+func (cfg daemonConfig) allInboxes() []inbox {
+	inboxes := cfg.inboxes
+	if cfg.src != "" {
+		inboxes = append([]inbox{defaultInbox(cfg.src)}, inboxes...)
+	}
+	return inboxes
+}
+
+func (cfg daemonConfig) enabled() bool {
+	return len(cfg.allInboxes()) > 0
+}
+
+func (cfg daemonConfig) validate() error {
+	seen := map[string]bool{}
+	for _, in := range cfg.allInboxes() {
+		dir := filepath.Clean(in.dir)
+		if seen[dir] {
+			return fmt.Errorf("inbox %v configured twice", in.dir)
+		}
+		seen[dir] = true
+	}
+	return nil
+}
+
+func daemonFlags(fs *flag.FlagSet) func() (daemonConfig, error) {
+	src := fs.String("daemon", "", "operations source dir for daemon")
+	archiveAfter := fs.Duration("archive-after", 0, "daemon archives done tasks older than this (0 disables)")
+	remind := fs.String("remind", "0s", "comma separated times before due when daemon reminds, 0s means overdue")
+	remindWebhook := fs.String("remind-webhook", "", "url daemon posts reminders to")
+	remindOutbox := fs.String("remind-outbox", "", "dir daemon writes reminder files to")
+	remindState := fs.String("remind-state", defaultRemindState, "file keeping already fired reminders")
+	metricsAddr := fs.String("metrics-addr", "", "address daemon serves /metrics and /healthz on")
+	healthMaxAge := fs.Duration("health-max-age", defaultHealthMaxAge, "/healthz fails when no save succeeded for this long")
+	configFp := fs.String("config", "", "json file with daemon settings, reloaded on SIGHUP")
+	notesCfg := notesFlags(fs)
+
+	return func() (daemonConfig, error) {
+		thresholds, err := parseDurations(*remind)
+		if err != nil {
+			return daemonConfig{}, fmt.Errorf("invalid remind thresholds %q: %w", *remind, err)
+		}
+		cfg := daemonConfig{
+			src:           *src,
+			archiveAfter:  *archiveAfter,
+			remind:        thresholds,
+			remindWebhook: *remindWebhook,
+			remindOutbox:  *remindOutbox,
+			remindState:   *remindState,
+			metricsAddr:   *metricsAddr,
+			healthMaxAge:  *healthMaxAge,
+			notes:         notesCfg(),
+		}
+
+		if *configFp != "" {
+			fc, err := loadConfigFile(*configFp)
+			if err != nil {
+				return daemonConfig{}, err
+			}
+			if err := fc.apply(&cfg, setFlags(fs)); err != nil {
+				return daemonConfig{}, fmt.Errorf("invalid config %v: %w", *configFp, err)
+			}
+		}
+		if err := cfg.validate(); err != nil {
+			return daemonConfig{}, err
+		}
+		return cfg, nil
+	}
+}
+
+// shutdownSignals end the daemon, reloadSignals make it read its
+// configuration again.
+var (
+	shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	reloadSignals   = []os.Signal{syscall.SIGHUP}
+)
+
+func daemon(cfg daemonConfig, reload func() (daemonConfig, error)) {
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
 
 	s := db.GetStorage()
-	s.StartSaveEveryMinute()
-	monitorOperations(src, s)
+	health := instrument(s, cfg.healthMaxAge)
+	s.StartSaveEveryMinute(ctx)
+	if cfg.metricsAddr != "" {
+		serveMonitoring(ctx, cfg.metricsAddr, s, health)
+	}
 
-	select {}
+	runDaemon(ctx, cfg, func() (daemonConfig, error) {
+		next, err := reload()
+		if err != nil {
+			return next, err
+		}
+		health.setMaxAge(next.healthMaxAge)
+		return next, nil
+	}, s)
+	finalSave(s)
 }
 
-func monitorOperations(src string, s *db.Storage) {
-	ticker := time.NewTicker(10 * time.Second)
+// runDaemon runs the daemon loops until ctx is done. On SIGHUP it loads the
+// configuration with reload and restarts the loops with it, a broken
+// configuration keeps the current one running.
+func runDaemon(ctx context.Context, cfg daemonConfig, reload func() (daemonConfig, error), s *db.Storage) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, reloadSignals...)
+	defer signal.Stop(hup)
 
-	go func() {
-		for {
-			<-ticker.C
-			c := dirOperations(src, s)
-			logger.Info("made operations", "counter", c)
+	superviseDaemon(ctx, hup, cfg, reload, s)
+}
+
+func superviseDaemon(ctx context.Context, hup <-chan os.Signal, cfg daemonConfig, reload func() (daemonConfig, error), s *db.Storage) {
+	for {
+		loopCtx, cancel := context.WithCancel(ctx)
+		wg := startDaemon(loopCtx, cfg, s)
+
+		next, ok := waitReload(ctx, hup, reload)
+		cancel()
+		wg.Wait()
+		if !ok {
+			logger.Info("Daemon stopped")
+			return
 		}
-	}()
+		cfg = next
+	}
 }
 
-func dirOperations(src string, s *db.Storage) uint {
-	var counter uint = 0
+func waitReload(ctx context.Context, hup <-chan os.Signal, reload func() (daemonConfig, error)) (daemonConfig, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return daemonConfig{}, false
+		case <-hup:
+		}
+
+		cfg, err := reload()
+		if err != nil {
+			logger.Error("Failed reload configuration", "error", err)
+			continue
+		}
+		logger.Info("Configuration reloaded")
+		return cfg, true
+	}
+}
 
-	entries, err := os.ReadDir(src)
-	if err != nil {
-		msg := fmt.Sprintf("Failed read dir %v", src)
-		logger.Error(msg, "error", err)
-		return counter
+func finalSave(s *db.Storage) {
+	if err := s.Save(); err != nil {
+		logger.Error("Failed final save", "error", err)
+	} else {
+		logger.Info("Tasks state saved on shutdown")
 	}
+}
 
-	filePaths := []string{}
-	for _, item := range entries {
-		if !item.IsDir() {
-			filePaths = append(filePaths, filepath.Join(src, item.Name()))
+// startDaemon runs the daemon loops in background goroutines over s, which
+// may be shared with other parts of the process like the HTTP API. They stop
+// when ctx is done, the returned group waits for them.
+func startDaemon(ctx context.Context, cfg daemonConfig, s *db.Storage) *sync.WaitGroup {
+	inboxes := cfg.allInboxes()
+	dirs := make([]string, len(inboxes))
+	for i, in := range inboxes {
+		dirs[i] = in.dir
+	}
+	wd, _ := os.Getwd()
+	logger.Info("Daemon started", "wd", wd, "inboxes", dirs, "archiveAfter", cfg.archiveAfter, "remind", cfg.remind)
+
+	wg := &sync.WaitGroup{}
+	if len(inboxes) > 0 {
+		a := startApplier(ctx, wg, s)
+		for _, in := range inboxes {
+			monitorInbox(ctx, wg, in, a.apply)
 		}
 	}
+	monitorReminders(ctx, wg, newScheduler(cfg), s)
+	if cfg.archiveAfter > 0 {
+		monitorArchive(ctx, wg, cfg.archiveAfter, s)
+	}
+	if cfg.notes.url != "" {
+		monitorNotes(ctx, wg, cfg.notes.syncer(), s)
+	}
+	return wg
+}
+
+// every calls fn each period in a goroutine tracked by wg until ctx is done.
+func every(ctx context.Context, wg *sync.WaitGroup, period time.Duration, fn func()) {
+	ticker := time.NewTicker(period)
 
-	for _, fp := range filePaths {
-		if err := makeOperation(fp, s); err != nil {
-			logger.Error("Failed makeOperation", "fp", fp, "error", err)
-		} else {
-			counter++
-			if err := os.Remove(fp); err != nil {
-				logger.Error("Failed delete operation file", "fp", fp)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
 			}
 		}
+	}()
+}
+
+func newScheduler(cfg daemonConfig) *reminders.Scheduler {
+	notifiers := []reminders.Notifier{
+		reminders.LogNotifier{},
+		reminders.HookNotifier{Runner: hookRunner},
+	}
+	if cfg.remindWebhook != "" {
+		notifiers = append(notifiers, reminders.NewWebhookNotifier(cfg.remindWebhook))
 	}
+	if cfg.remindOutbox != "" {
+		notifiers = append(notifiers, reminders.OutboxNotifier{Dir: cfg.remindOutbox})
+	}
+
+	return reminders.NewScheduler(cfg.remind, notifiers, cfg.remindState).
+		WithOverdue(reminders.OverdueHookNotifier{Runner: hookRunner})
+}
 
-	return counter
+func monitorReminders(ctx context.Context, wg *sync.WaitGroup, sched *reminders.Scheduler, s *db.Storage) {
+	every(ctx, wg, time.Minute, func() {
+		if c := sched.Check(s.ListTasks(), time.Now()); c > 0 {
+			logger.Info("reminders fired", "counter", c)
+		}
+	})
+}
+
+func monitorArchive(ctx context.Context, wg *sync.WaitGroup, age time.Duration, s *db.Storage) {
+	every(ctx, wg, time.Minute, func() {
+		if ids := s.ArchiveDone(age); len(ids) > 0 {
+			logger.Info("tasks archived", "ids", ids)
+		}
+	})
 }
 
 const (
@@ -79,82 +276,110 @@ var allowedOp = map[string]bool{
 	newOpName:    true,
 }
 
-func makeOperation(src string, s *db.Storage) error {
-	var o Operation
-
-	operation := strings.SplitN(filepath.Base(src), "_", 2)[0]
+// operationName returns the operation type encoded in the file name, or
+// "unknown" for files no operation matches.
+func operationName(fp string) string {
+	operation := strings.SplitN(filepath.Base(fp), "_", 2)[0]
 	if !allowedOp[operation] {
-		return fmt.Errorf("unknonw operation %v", operation)
-	}
-
-	jsonFile, err := os.Open(src)
-	if err != nil {
-		return err
+		return "unknown"
 	}
-	defer jsonFile.Close()
+	return operation
+}
 
-	byteValue, _ := io.ReadAll(jsonFile)
+func parseOperation(operation string, data []byte) (Operation, error) {
+	var o Operation
 
 	switch operation {
 	case deleteOpName:
 		var d deleteOperation
-		if err := json.Unmarshal(byteValue, &d); err != nil {
-			return err
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, err
 		}
 		o = &d
 	case markOpName:
 		var m markDoneOperation
-		if err := json.Unmarshal(byteValue, &m); err != nil {
-			return err
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
 		}
 		o = &m
 	case newOpName:
 		var n newOperation
-		if err := json.Unmarshal(byteValue, &n); err != nil {
-			return err
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, err
 		}
 		o = &n
+	default:
+		return nil, fmt.Errorf("unknonw operation %v", operation)
 	}
 
-	return o.make(s)
+	return o, nil
 }
 
 type Operation interface {
-	make(s *db.Storage) error
+	make(s *db.Storage, list string) error
 }
 
 type idOperation struct {
 	Id uuid.UUID `json:"id"`
 }
 
+// inList fails when list is set and the task isn't part of it, so an inbox
+// can only change the tasks of its own list.
+func (o idOperation) inList(s *db.Storage, list string) error {
+	if list == "" {
+		return nil
+	}
+	if t, ok := s.GetTask(o.Id.String()); ok && t.List != list {
+		return fmt.Errorf("task %v is not in list %q", o.Id, list)
+	}
+	return nil
+}
+
 type deleteOperation struct {
 	idOperation
 }
 
-func (d *deleteOperation) make(s *db.Storage) error {
-	return s.DeleteTask(d.Id.String())
+func (d *deleteOperation) make(s *db.Storage, list string) error {
+	if err := d.inList(s, list); err != nil {
+		return err
+	}
+	return applyDelete(s, d.Id.String(), hooks.SourceDaemon)
 }
 
 type markDoneOperation struct {
 	idOperation
 }
 
-func (d *markDoneOperation) make(s *db.Storage) error {
-	return s.MarkDone(d.Id.String())
+func (d *markDoneOperation) make(s *db.Storage, list string) error {
+	if err := d.inList(s, list); err != nil {
+		return err
+	}
+	_, err := applyMarkDone(s, d.Id.String(), hooks.SourceDaemon)
+	return err
 }
 
 type newOperation struct {
-	Time        time.Time `json:"time"`
-	Name        string    `json:"name"`
-	Description string    `json:"desc"`
-}
-
-func (c *newOperation) make(s *db.Storage) error {
-	return s.AddTask(
-		db.NewTaskBuilder(db.UuidIdGenerator).
-			WithName(c.Name).
-			WithDescription(c.Description).
-			WithTime(c.Time).
-			Build(),
-	)
+	Time        dueTime  `json:"time"`
+	Name        string   `json:"name"`
+	Description string   `json:"desc"`
+	Tags        []string `json:"tags"`
+	List        string   `json:"list"`
+}
+
+// make creates the task in list, or in the list named by the file when the
+// inbox has none.
+func (c *newOperation) make(s *db.Storage, list string) error {
+	if list == "" {
+		list = c.List
+	}
+	t := db.NewTaskBuilder(db.UuidIdGenerator).
+		WithName(c.Name).
+		WithDescription(c.Description).
+		WithTime(c.Time.Time).
+		WithTags(c.Tags...).
+		WithList(list).
+		Build()
+
+	_, err := applyAdd(s, t, hooks.SourceDaemon)
+	return err
 }
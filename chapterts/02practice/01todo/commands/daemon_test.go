@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"todo/cli/db"
+	"todo/cli/hooks"
+)
+
+func TestDaemonFlags_ConfigFile(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "todo.json")
+	config := `{"daemon": "./ops", "archive_after": "24h", "remind": ["1h", "0s"], "remind_outbox": "./outbox"}`
+	if err := os.WriteFile(fp, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	load := daemonFlags(fs)
+	if err := fs.Parse([]string{"-config", fp, "-remind-outbox", "./flag-outbox"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.src != "./ops" || cfg.archiveAfter != 24*time.Hour || !reflect.DeepEqual(cfg.remind, []time.Duration{time.Hour, 0}) {
+		t.Errorf("config file values not applied: %+v", cfg)
+	}
+	if cfg.remindOutbox != "./flag-outbox" {
+		t.Errorf("flags must override config file, got %q", cfg.remindOutbox)
+	}
+	if cfg.remindState != defaultRemindState || cfg.healthMaxAge != defaultHealthMaxAge {
+		t.Errorf("defaults must stay for missing values: %+v", cfg)
+	}
+
+	os.WriteFile(fp, []byte(`{"archive_afer": "24h"}`), 0o644)
+	if _, err := load(); err == nil {
+		t.Errorf("expected error for unknown config field")
+	}
+}
+
+func TestSuperviseDaemon(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+
+	src := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	hup := make(chan os.Signal)
+
+	reloads := make(chan daemonConfig)
+	fail := true
+	reload := func() (daemonConfig, error) {
+		if fail {
+			fail = false
+			return daemonConfig{}, errors.New("broken config")
+		}
+		cfg := daemonConfig{src: src}
+		reloads <- cfg
+		return cfg, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		superviseDaemon(ctx, hup, daemonConfig{src: src}, reload, s)
+		close(done)
+	}()
+
+	hup <- syscall.SIGHUP
+	hup <- syscall.SIGHUP
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("daemon didn't reload after a broken configuration")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("daemon didn't stop after cancel")
+	}
+}
+
+func TestDirOperations_StopsOnCancel(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+
+	src := t.TempDir()
+	for _, name := range []string{"new_1.json", "new_2.json"} {
+		os.WriteFile(filepath.Join(src, name), []byte(`{"name": "task"}`), 0o644)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c := defaultInbox(src).process(ctx, applyTo(s)); c != 0 {
+		t.Errorf("expected no operations after cancel, got %d", c)
+	}
+	if entries, _ := os.ReadDir(src); len(entries) != 2 {
+		t.Errorf("operation files must stay for the next run, got %d", len(entries))
+	}
+}
+
+func TestNewScheduler_OverdueHooksWithoutZeroThreshold(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "events")
+	for _, name := range []string{"on-overdue", "on-remind"} {
+		script := "#!/bin/sh\necho \"$TODO_EVENT $TODO_TASK_ID\" >> " + log + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(r *hooks.Runner) { hookRunner = r }(hookRunner)
+	hookRunner = hooks.NewRunner(dir, time.Second)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	load := daemonFlags(fs)
+	fs.Parse([]string{"-remind", "1h", "-remind-state", filepath.Join(t.TempDir(), "reminders.json")})
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	late := db.NewTaskBuilder(db.UuidIdGenerator).WithName("late").WithTime(now.Add(-time.Minute)).Build()
+	soon := db.NewTaskBuilder(db.UuidIdGenerator).WithName("soon").WithTime(now.Add(30 * time.Minute)).Build()
+	sched := newScheduler(cfg)
+	sched.Check([]*db.Task{late, soon}, now)
+	sched.Check([]*db.Task{late, soon}, now.Add(time.Hour))
+
+	data, _ := os.ReadFile(log)
+	events := strings.Split(strings.TrimSpace(string(data)), "\n")
+	sort.Strings(events)
+	expected := []string{
+		"on-overdue " + late.ID.String(),
+		"on-overdue " + soon.ID.String(),
+		"on-remind " + late.ID.String(),
+		"on-remind " + soon.ID.String(),
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
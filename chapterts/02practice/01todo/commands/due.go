@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"encoding/json"
+	"time"
+	"todo/cli/dates"
+)
+
+const tzEnv = "TODO_TZ"
+
+var dueParser = dates.NewParser(time.Local)
+
+func setTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	dueParser = dates.NewParser(loc)
+
+	return nil
+}
+
+func parseDue(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return dueParser.Parse(s)
+}
+
+// dueTime accepts both RFC3339 timestamps and natural language phrases
+// in daemon operation files.
+type dueTime struct {
+	time.Time
+}
+
+func (d *dueTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	t, err := parseDue(s)
+	if err != nil {
+		return err
+	}
+	d.Time = t
+
+	return nil
+}
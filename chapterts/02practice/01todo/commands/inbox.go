@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"todo/cli/db"
+)
+
+const (
+	defaultInboxInterval = 10 * time.Second
+	signatureExt         = ".sig"
+)
+
+// inbox is a directory the daemon takes operation files from.
+type inbox struct {
+	dir        string
+	list       string
+	ops        map[string]bool
+	interval   time.Duration
+	requireSig bool
+	secret     []byte
+}
+
+func defaultInbox(dir string) inbox {
+	return inbox{dir: dir, interval: defaultInboxInterval}
+}
+
+// allows reports whether the inbox accepts the operation, all of them are
+// accepted when no list of operations is configured.
+func (in inbox) allows(op string) bool {
+	return in.ops == nil || in.ops[op]
+}
+
+type applyFunc func(ctx context.Context, o Operation, list string) error
+
+type applyRequest struct {
+	o      Operation
+	list   string
+	result chan error
+}
+
+// applier applies operations of all inboxes one at a time, in the order
+// they were read.
+type applier struct {
+	requests chan applyRequest
+}
+
+func startApplier(ctx context.Context, wg *sync.WaitGroup, s *db.Storage) *applier {
+	a := &applier{requests: make(chan applyRequest)}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case req := <-a.requests:
+				req.result <- req.o.make(s, req.list)
+			}
+		}
+	}()
+
+	return a
+}
+
+// apply waits for o to be applied. It gives up only while the operation is
+// still queued, an operation being applied always finishes.
+func (a *applier) apply(ctx context.Context, o Operation, list string) error {
+	req := applyRequest{o: o, list: list, result: make(chan error, 1)}
+	select {
+	case a.requests <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-req.result
+}
+
+func monitorInbox(ctx context.Context, wg *sync.WaitGroup, in inbox, apply applyFunc) {
+	every(ctx, wg, in.interval, func() {
+		c := in.process(ctx, apply)
+		logger.Info("made operations", "dir", in.dir, "counter", c)
+	})
+}
+
+// process applies operation files of the inbox. When ctx is done it
+// finishes the current file and leaves the rest for the next run.
+func (in inbox) process(ctx context.Context, apply applyFunc) uint {
+	var counter uint = 0
+
+	entries, err := os.ReadDir(in.dir)
+	if err != nil {
+		msg := fmt.Sprintf("Failed read dir %v", in.dir)
+		logger.Error(msg, "error", err)
+		return counter
+	}
+
+	filePaths := []string{}
+	for _, item := range entries {
+		if !item.IsDir() && filepath.Ext(item.Name()) != signatureExt {
+			filePaths = append(filePaths, filepath.Join(in.dir, item.Name()))
+		}
+	}
+	queueFiles.Set(float64(len(filePaths)), in.dir)
+
+	for _, fp := range filePaths {
+		if ctx.Err() != nil {
+			break
+		}
+		op := operationName(fp)
+		if err := in.processFile(ctx, fp, apply); err != nil {
+			opsFailed.Inc(in.dir, op)
+			logger.Error("Failed makeOperation", "fp", fp, "error", err)
+		} else {
+			opsProcessed.Inc(in.dir, op)
+			counter++
+			if err := os.Remove(fp); err != nil {
+				logger.Error("Failed delete operation file", "fp", fp)
+			}
+			if err := os.Remove(fp + signatureExt); err != nil && !os.IsNotExist(err) {
+				logger.Error("Failed delete signature file", "fp", fp+signatureExt)
+			}
+		}
+	}
+
+	return counter
+}
+
+func (in inbox) processFile(ctx context.Context, fp string, apply applyFunc) error {
+	operation := strings.SplitN(filepath.Base(fp), "_", 2)[0]
+	if allowedOp[operation] && !in.allows(operation) {
+		return fmt.Errorf("operation %v not allowed in %v", operation, in.dir)
+	}
+
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+	if in.requireSig {
+		if err := verifySignature(data, fp+signatureExt, in.secret); err != nil {
+			return err
+		}
+	}
+
+	o, err := parseOperation(operation, data)
+	if err != nil {
+		return err
+	}
+	return apply(ctx, o, in.list)
+}
+
+var errBadSignature = errors.New("signature doesn't match")
+
+// verifySignature checks the hex encoded HMAC-SHA256 of data kept in the
+// sidecar file sigFp.
+func verifySignature(data []byte, sigFp string, secret []byte) error {
+	sig, err := os.ReadFile(sigFp)
+	if err != nil {
+		return fmt.Errorf("missing signature: %w", err)
+	}
+	got, err := hex.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+func applyTo(s *db.Storage) applyFunc {
+	return func(ctx context.Context, o Operation, list string) error {
+		return o.make(s, list)
+	}
+}
+
+func sign(data, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeOperation(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInbox_Process(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+	secret := []byte("s3cret")
+
+	personal := db.NewTaskBuilder(db.UuidIdGenerator).WithName("personal").Build()
+	s.AddTask(personal)
+
+	work := defaultInbox(t.TempDir())
+	work.list = "work"
+	work.ops = map[string]bool{newOpName: true, markOpName: true}
+	work.requireSig = true
+	work.secret = secret
+
+	signed := `{"name": "Review PR", "list": "other"}`
+	writeOperation(t, work.dir, "new_1.json", signed)
+	writeOperation(t, work.dir, "new_1.json.sig", sign([]byte(signed), secret))
+	writeOperation(t, work.dir, "new_2.json", `{"name": "Unsigned"}`)
+	writeOperation(t, work.dir, "new_3.json", `{"name": "Forged"}`)
+	writeOperation(t, work.dir, "new_3.json.sig", sign([]byte(`{"name": "Other"}`), secret))
+	deleteOp := `{"id": "` + personal.ID.String() + `"}`
+	writeOperation(t, work.dir, "delete_1.json", deleteOp)
+	writeOperation(t, work.dir, "delete_1.json.sig", sign([]byte(deleteOp), secret))
+	markOp := `{"id": "` + personal.ID.String() + `"}`
+	writeOperation(t, work.dir, "mark_1.json", markOp)
+	writeOperation(t, work.dir, "mark_1.json.sig", sign([]byte(markOp), secret))
+
+	if c := work.process(context.Background(), applyTo(s)); c != 1 {
+		t.Errorf("expected only the signed new operation to be applied, got %d", c)
+	}
+
+	tasks := s.ListTasks()
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Name == "Review PR" && task.List != "work" {
+			t.Errorf("inbox list must win over the file, got %q", task.List)
+		}
+		if task.Name == "personal" && task.Done {
+			t.Errorf("inbox must not change tasks of other lists")
+		}
+	}
+
+	entries, _ := os.ReadDir(work.dir)
+	remaining := []string{}
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	expected := []string{"delete_1.json", "delete_1.json.sig", "mark_1.json", "mark_1.json.sig", "new_2.json", "new_3.json", "new_3.json.sig"}
+	if len(remaining) != len(expected) {
+		t.Errorf("expected failed files to stay, got %v", remaining)
+	}
+	for i := range expected {
+		if i < len(remaining) && remaining[i] != expected[i] {
+			t.Errorf("expected failed files %v to stay, got %v", expected, remaining)
+			break
+		}
+	}
+}
+
+// countingOperation records how many operations run at the same time.
+type countingOperation struct {
+	active, maxActive *atomic.Int32
+}
+
+func (c countingOperation) make(s *db.Storage, list string) error {
+	n := c.active.Add(1)
+	for {
+		m := c.maxActive.Load()
+		if n <= m || c.maxActive.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	c.active.Add(-1)
+	return nil
+}
+
+func TestApplier_Serializes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	a := startApplier(ctx, wg, nil)
+
+	var active, maxActive atomic.Int32
+	var callers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		callers.Add(1)
+		go func() {
+			defer callers.Done()
+			for j := 0; j < 10; j++ {
+				if err := a.apply(ctx, countingOperation{&active, &maxActive}, ""); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	callers.Wait()
+
+	if maxActive.Load() != 1 {
+		t.Errorf("expected operations applied one at a time, got %d at once", maxActive.Load())
+	}
+
+	cancel()
+	wg.Wait()
+	if err := a.apply(ctx, countingOperation{&active, &maxActive}, ""); err == nil {
+		t.Errorf("expected error after applier stopped")
+	}
+}
+
+func TestDaemonFlags_Inboxes(t *testing.T) {
+	t.Setenv("TEST_INBOX_SECRET", "s3cret")
+
+	var tests = []struct {
+		name    string
+		config  string
+		args    []string
+		wantErr bool
+	}{
+		{"valid", `{"inboxes": [{"dir": "./github", "list": "work", "ops": ["new"], "interval": "1s", "require_signature": true, "secret_env": "TEST_INBOX_SECRET"}]}`, []string{"-daemon", "./ops"}, false},
+		{"unknown op", `{"inboxes": [{"dir": "./github", "ops": ["rename"]}]}`, nil, true},
+		{"missing secret", `{"inboxes": [{"dir": "./github", "require_signature": true, "secret_env": "TEST_INBOX_MISSING"}]}`, nil, true},
+		{"duplicate dir", `{"inboxes": [{"dir": "./ops/"}]}`, []string{"-daemon", "ops"}, true},
+		{"no dir", `{"inboxes": [{"list": "work"}]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := filepath.Join(t.TempDir(), "todo.json")
+			os.WriteFile(fp, []byte(tt.config), 0o644)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			load := daemonFlags(fs)
+			fs.Parse(append([]string{"-config", fp}, tt.args...))
+
+			cfg, err := load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			inboxes := cfg.allInboxes()
+			if len(inboxes) != 2 || inboxes[0].dir != "./ops" {
+				t.Fatalf("unexpected inboxes %+v", inboxes)
+			}
+			in := inboxes[1]
+			if in.list != "work" || in.interval != time.Second || !in.requireSig || string(in.secret) != "s3cret" || !in.allows(newOpName) || in.allows(markOpName) {
+				t.Errorf("unexpected inbox %+v", in)
+			}
+		})
+	}
+}
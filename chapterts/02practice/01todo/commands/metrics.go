@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+	"todo/cli/db"
+	"todo/cli/metrics"
+)
+
+const defaultHealthMaxAge = 3 * time.Minute
+
+var (
+	registry = metrics.NewRegistry()
+
+	opsProcessed = registry.NewCounter("todo_operations_processed_total", "Operation files applied by the daemon.", "dir", "op")
+	opsFailed    = registry.NewCounter("todo_operations_failed_total", "Operation files the daemon failed to apply.", "dir", "op")
+	queueFiles   = registry.NewGauge("todo_queue_files", "Files found in the operations dir on the last scan.", "dir")
+	taskCount    = registry.NewGauge("todo_tasks", "Tasks by state.", "state")
+	lastSave     = registry.NewGauge("todo_last_save_timestamp_seconds", "Unix time of the last successful save.")
+	saveFailures = registry.NewCounter("todo_save_failures_total", "Failed saves of the storage files.")
+	saveLatency  = registry.NewHistogram("todo_save_duration_seconds", "Time spent saving the storage files.",
+		[]float64{.001, .005, .01, .05, .1, .5, 1, 5})
+)
+
+// saveHealth tracks when s was last saved successfully.
+type saveHealth struct {
+	mu       sync.Mutex
+	started  time.Time
+	lastSave time.Time
+	maxAge   time.Duration
+}
+
+func newSaveHealth(maxAge time.Duration) *saveHealth {
+	return &saveHealth{started: time.Now(), maxAge: maxAge}
+}
+
+func (h *saveHealth) observe(took time.Duration, err error) {
+	saveLatency.Observe(took.Seconds())
+	if err != nil {
+		saveFailures.Inc()
+		return
+	}
+
+	now := time.Now()
+	lastSave.Set(float64(now.UnixNano()) / 1e9)
+
+	h.mu.Lock()
+	h.lastSave = now
+	h.mu.Unlock()
+}
+
+// check reports whether a save succeeded within maxAge. The process start
+// counts as a save so a fresh daemon gets one period of grace.
+func (h *saveHealth) check(now time.Time) (bool, time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last := h.started
+	if h.lastSave.After(last) {
+		last = h.lastSave
+	}
+	return now.Sub(last) <= h.maxAge, h.lastSave
+}
+
+// setMaxAge changes the allowed save age, e.g. after a configuration reload.
+func (h *saveHealth) setMaxAge(maxAge time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxAge = maxAge
+}
+
+func (h *saveHealth) limit() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.maxAge
+}
+
+// instrument records save metrics of s and returns the health state fed by
+// them.
+func instrument(s *db.Storage, maxAge time.Duration) *saveHealth {
+	h := newSaveHealth(maxAge)
+	s.OnSave(h.observe)
+	return h
+}
+
+func countTasks(s *db.Storage, now time.Time) {
+	var pending, overdue, done int
+	for _, t := range s.ListTasks() {
+		switch {
+		case t.Done:
+			done++
+		case !t.Time.IsZero() && t.Time.Before(now):
+			overdue++
+		default:
+			pending++
+		}
+	}
+
+	taskCount.Set(float64(pending), "pending")
+	taskCount.Set(float64(overdue), "overdue")
+	taskCount.Set(float64(done), "done")
+	taskCount.Set(float64(len(s.ListArchivedTasks())), "archived")
+}
+
+// handleMonitoring registers /metrics and /healthz on mux.
+func handleMonitoring(mux *http.ServeMux, s *db.Storage, h *saveHealth) {
+	metricsHandler := registry.Handler()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		countTasks(s, time.Now())
+		metricsHandler.ServeHTTP(w, r)
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		ok, last := h.check(time.Now())
+
+		resp := map[string]any{"status": "ok"}
+		if !last.IsZero() {
+			resp["last_save"] = last
+		}
+		if !ok {
+			resp["status"] = "unhealthy"
+			resp["message"] = "no successful save within " + h.limit().String()
+			writeJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+func serveMonitoring(ctx context.Context, addr string, s *db.Storage, h *saveHealth) {
+	mux := http.NewServeMux()
+	handleMonitoring(mux, s, h)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		logger.Info("Metrics server started", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server stopped", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+func TestSaveHealth(t *testing.T) {
+	h := newSaveHealth(time.Minute)
+	h.started = time.Now().Add(-2 * time.Minute)
+
+	if ok, _ := h.check(time.Now()); ok {
+		t.Errorf("expected unhealthy when nothing was saved since start")
+	}
+
+	failures := saveFailures.Value()
+	h.observe(time.Millisecond, errors.New("disk full"))
+	if ok, _ := h.check(time.Now()); ok {
+		t.Errorf("failed save must not make daemon healthy")
+	}
+	if saveFailures.Value() != failures+1 {
+		t.Errorf("failed save not counted")
+	}
+
+	h.observe(time.Millisecond, nil)
+	ok, last := h.check(time.Now())
+	if !ok || last.IsZero() {
+		t.Errorf("expected healthy after save, got %v %v", ok, last)
+	}
+	if ok, _ := h.check(time.Now().Add(2 * time.Minute)); ok {
+		t.Errorf("expected unhealthy when last save is too old")
+	}
+	h.setMaxAge(time.Hour)
+	if ok, _ := h.check(time.Now().Add(2 * time.Minute)); !ok {
+		t.Errorf("expected reloaded max age to apply")
+	}
+}
+
+func TestDirOperations_Metrics(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+
+	src := t.TempDir()
+	files := map[string]string{
+		"new_1.json":    `{"name": "Send invoice"}`,
+		"new_2.json":    `not json`,
+		"delete_1.json": `{"id": "missing"}`,
+		"rename_1.json": `{}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if c := defaultInbox(src).process(context.Background(), applyTo(s)); c != 1 {
+		t.Errorf("expected 1 applied operation, got %d", c)
+	}
+
+	if opsProcessed.Value(src, "new") != 1 {
+		t.Errorf("processed new operation not counted")
+	}
+	if opsFailed.Value(src, "new") != 1 || opsFailed.Value(src, "delete") != 1 || opsFailed.Value(src, "unknown") != 1 {
+		t.Errorf("failed operations not counted by type")
+	}
+	if v := queueFiles.Value(src); v != 4 {
+		t.Errorf("expected queue size 4, got %v", v)
+	}
+}
+
+func TestHandleMonitoring(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := db.GetStorage()
+	h := instrument(s, time.Minute)
+
+	done := db.NewTaskBuilder(db.UuidIdGenerator).WithName("done").Build()
+	overdue := db.NewTaskBuilder(db.UuidIdGenerator).WithName("overdue").WithTime(time.Now().Add(-time.Hour)).Build()
+	s.AddTask(done)
+	s.AddTask(overdue)
+	s.AddTask(db.NewTaskBuilder(db.UuidIdGenerator).WithName("pending").Build())
+	s.MarkDone(done.ID.String())
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	handleMonitoring(mux, s, h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		`todo_tasks{state="pending"} 1`,
+		`todo_tasks{state="overdue"} 1`,
+		`todo_tasks{state="done"} 1`,
+		`todo_tasks{state="archived"} 0`,
+		`todo_save_duration_seconds_count `,
+		`todo_last_save_timestamp_seconds `,
+	} {
+		if !strings.Contains(rec.Body.String(), line) {
+			t.Errorf("metrics missing %q:\n%s", line, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected healthy, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	h.maxAge = 0
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected unhealthy, got %d %s", rec.Code, rec.Body.String())
+	}
+}
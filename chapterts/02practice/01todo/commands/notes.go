@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"os"
+	"sync"
+	"time"
+	"todo/cli/db"
+	"todo/cli/hooks"
+	"todo/cli/notesync"
+)
+
+const (
+	defaultNotesMapping = "./notes_sync.json"
+	notesTokenEnv       = "TODO_NOTES_TOKEN"
+)
+
+type notesConfig struct {
+	url     string
+	token   string
+	author  string
+	mapping string
+}
+
+func notesFlags(fs *flag.FlagSet) func() notesConfig {
+	url := fs.String("notes-url", "", "base url of the notes API server, e.g. http://127.0.0.1:8090")
+	author := fs.String("notes-author", "", "author id set on created notes")
+	mapping := fs.String("notes-mapping", defaultNotesMapping, "file linking tasks to their notes")
+	token := fs.String("notes-token", "", "token of the notes author, default $"+notesTokenEnv)
+
+	return func() notesConfig {
+		cfg := notesConfig{url: *url, token: *token, author: *author, mapping: *mapping}
+		if cfg.token == "" {
+			cfg.token = os.Getenv(notesTokenEnv)
+		}
+		return cfg
+	}
+}
+
+func (c notesConfig) syncer() *notesync.Syncer {
+	return &notesync.Syncer{
+		Client:    notesync.NewClient(c.url, c.token),
+		MappingFp: c.mapping,
+		AuthorID:  c.author,
+	}
+}
+
+func pushCmd(fs *flag.FlagSet) func(args []string) {
+	notesCfg := notesFlags(fs)
+
+	return func(args []string) {
+		cfg := notesCfg()
+		if cfg.url == "" {
+			logger.Warn("invalid push call", "expected", "push -notes-url <url>")
+			return
+		}
+
+		res, err := cfg.syncer().Push(context.Background(), db.GetStorage().ListTasks())
+		if err != nil {
+			logger.Warn("push error", "error", err)
+			return
+		}
+		logger.Info("tasks pushed", "result", res)
+	}
+}
+
+func pullCmd(fs *flag.FlagSet) func(args []string) {
+	notesCfg := notesFlags(fs)
+	applySettings := settingsFlags(fs)
+
+	return func(args []string) {
+		if err := applySettings(); err != nil {
+			logger.Warn("invalid settings", "error", err)
+			return
+		}
+		cfg := notesCfg()
+		if cfg.url == "" {
+			logger.Warn("invalid pull call", "expected", "pull -notes-url <url>")
+			return
+		}
+
+		s := db.GetStorage()
+		res, err := pullNotes(context.Background(), cfg.syncer(), s)
+		if err != nil {
+			logger.Warn("pull error", "error", err)
+			return
+		}
+		if err := s.Save(); err != nil {
+			logger.Warn("pull error", "error", err)
+			return
+		}
+		logger.Info("notes pulled", "result", res)
+	}
+}
+
+// pullNotes imports notes not linked to a task yet as new tasks.
+func pullNotes(ctx context.Context, syncer *notesync.Syncer, s *db.Storage) (notesync.Result, error) {
+	return syncer.Pull(ctx, func(n notesync.Note) (*db.Task, error) {
+		desc := ""
+		if n.Description != nil {
+			desc = *n.Description
+		}
+		t := db.NewTaskBuilder(db.UuidIdGenerator).
+			WithName(n.Name).
+			WithDescription(desc).
+			Build()
+
+		return applyAdd(s, t, hooks.SourceNotes)
+	})
+}
+
+func monitorNotes(ctx context.Context, wg *sync.WaitGroup, syncer *notesync.Syncer, s *db.Storage) {
+	every(ctx, wg, time.Minute, func() {
+		res, err := syncer.Push(ctx, s.ListTasks())
+		if err != nil {
+			logger.Error("Failed push tasks to notes", "error", err)
+		} else if res.Created+res.Updated+res.Failed > 0 {
+			logger.Info("tasks pushed to notes", "result", res)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo/cli/db"
+	"todo/cli/notesync"
+	"todo/cli/notesync/notestest"
+)
+
+func TestPullNotes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	server := notestest.NewServer("nt_test")
+	defer server.Close()
+
+	desc := "from notes"
+	server.Put(notesync.Note{Name: "Read book", Description: &desc})
+	server.Put(notesync.Note{Name: "No description"})
+
+	s := db.GetStorage()
+	syncer := notesConfig{url: server.URL, token: server.Token, mapping: defaultNotesMapping}.syncer()
+	res, err := pullNotes(context.Background(), syncer, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 2 {
+		t.Fatalf("expected 2 imported notes, got %+v", res)
+	}
+
+	tasks := taskViews(s, s.ListTasks())
+	if len(tasks) != 2 || tasks[0].Name != "Read book" || tasks[0].Description != "from notes" || tasks[0].Seq == 0 {
+		t.Errorf("unexpected imported tasks %+v", tasks)
+	}
+
+	if res, _ := syncer.Push(context.Background(), s.ListTasks()); res.Created != 0 || len(server.Notes()) != 2 {
+		t.Errorf("imported tasks must not be duplicated on push, got %+v", res)
+	}
+}
+
+func TestDaemonFlags_Notes(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "todo.json")
+	os.WriteFile(fp, []byte(`{"notes_url": "http://notes:8090", "notes_author": "a1", "notes_token_env": "TEST_NOTES_TOKEN"}`), 0o644)
+
+	t.Setenv("TEST_NOTES_TOKEN", "nt_file")
+	t.Setenv(notesTokenEnv, "nt_env")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	load := daemonFlags(fs)
+	fs.Parse([]string{"-config", fp, "-notes-author", "a2"})
+
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := notesConfig{url: "http://notes:8090", token: "nt_file", author: "a2", mapping: defaultNotesMapping}
+	if cfg.notes != expected {
+		t.Errorf("expected %+v, got %+v", expected, cfg.notes)
+	}
+}
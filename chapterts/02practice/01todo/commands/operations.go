@@ -1,22 +1,55 @@
 package commands
 
 import (
+	"slices"
+	"time"
 	"todo/cli/db"
+	"todo/cli/hooks"
 
 	"github.com/google/uuid"
 )
 
-func listTasks() []*db.Task {
-	return db.GetStorage().ListTasks()
+type taskView struct {
+	ShortID string `json:"short_id"`
+	*db.Task
 }
 
-func task(id string) (*db.Task, bool) {
-	return db.GetStorage().GetTask(id)
+func taskViews(s *db.Storage, tasks []*db.Task) []taskView {
+	short := s.ShortIDs()
+	slices.SortFunc(tasks, db.CompareSeq)
+
+	views := make([]taskView, 0, len(tasks))
+	for _, t := range tasks {
+		views = append(views, taskView{short[t.ID.String()], t})
+	}
+	return views
 }
 
-func deleteTask(id string) error {
+func listTasks() []taskView {
 	s := db.GetStorage()
-	if err := s.DeleteTask(id); err != nil {
+	return taskViews(s, s.ListTasks())
+}
+
+func task(ref string) (*db.Task, error) {
+	s := db.GetStorage()
+	id, err := s.ResolveID(ref)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := s.GetTask(id); ok {
+		return t, nil
+	}
+	t, _ := s.GetArchivedTask(id)
+	return t, nil
+}
+
+func deleteTask(ref string) error {
+	s := db.GetStorage()
+	id, err := s.ResolveID(ref)
+	if err != nil {
+		return err
+	}
+	if err := applyDelete(s, id, hooks.SourceCLI); err != nil {
 		return err
 	}
 	if err := s.Save(); err != nil {
@@ -25,9 +58,13 @@ func deleteTask(id string) error {
 	return nil
 }
 
-func markDoneTask(id string) error {
+func markDoneTask(ref string) error {
 	s := db.GetStorage()
-	if err := s.MarkDone(id); err != nil {
+	id, err := s.ResolveID(ref)
+	if err != nil {
+		return err
+	}
+	if _, err := applyMarkDone(s, id, hooks.SourceCLI); err != nil {
 		return err
 	}
 	if err := s.Save(); err != nil {
@@ -36,14 +73,16 @@ func markDoneTask(id string) error {
 	return nil
 }
 
-func newTask(name, desc string) (*uuid.UUID, error) {
+func newTask(name, desc string, due time.Time, tags []string) (*uuid.UUID, error) {
 	t := db.NewTaskBuilder(db.UuidIdGenerator).
 		WithName(name).
 		WithDescription(desc).
+		WithTime(due).
+		WithTags(tags...).
 		Build()
 
 	s := db.GetStorage()
-	if err := s.AddTask(t); err != nil {
+	if _, err := applyAdd(s, t, hooks.SourceCLI); err != nil {
 		return nil, err
 	}
 	if err := s.Save(); err != nil {
@@ -52,3 +91,66 @@ func newTask(name, desc string) (*uuid.UUID, error) {
 
 	return &t.ID, nil
 }
+
+func listArchivedTasks() []taskView {
+	s := db.GetStorage()
+	return taskViews(s, s.ListArchivedTasks())
+}
+
+func archiveTasks(olderThan time.Duration) ([]string, error) {
+	s := db.GetStorage()
+	ids := s.ArchiveDone(olderThan)
+	if err := s.Save(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func restoreTask(ref string) error {
+	s := db.GetStorage()
+	id, err := s.ResolveID(ref)
+	if err != nil {
+		return err
+	}
+	if err := s.RestoreTask(id); err != nil {
+		return err
+	}
+	if err := s.Save(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// applyAdd stores t, as changed by the pre-add hooks, and returns the task
+// that was stored.
+func applyAdd(s *db.Storage, t *db.Task, source string) (*db.Task, error) {
+	t, err := hookRunner.PreAdd(source, t)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AddTask(t); err != nil {
+		return nil, err
+	}
+	hookRunner.Run(hooks.OnAdd, source, t)
+	return t, nil
+}
+
+func applyDelete(s *db.Storage, id, source string) error {
+	t, ok := s.GetTask(id)
+	if err := s.DeleteTask(id); err != nil {
+		return err
+	}
+	if ok {
+		hookRunner.Run(hooks.OnDelete, source, t)
+	}
+	return nil
+}
+
+func applyMarkDone(s *db.Storage, id, source string) (*db.Task, error) {
+	t, err := s.MarkDone(id)
+	if err != nil {
+		return nil, err
+	}
+	hookRunner.Run(hooks.OnDone, source, t)
+	return t, nil
+}
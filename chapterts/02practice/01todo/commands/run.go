@@ -2,69 +2,203 @@ package commands
 
 import (
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
+	"time"
+	"todo/cli/hooks"
 )
 
 var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
 
-func Run() {
-	var fDaemonSrc string
-	flag.StringVar(&fDaemonSrc, "daemon", "", "operations source dir for daemon")
+const (
+	hooksDirEnv        = "TODO_HOOKS_DIR"
+	defaultHooksDir    = "./hooks"
+	defaultHookTimeout = 10 * time.Second
+	defaultRemindState = "./reminders.json"
+)
+
+var hookRunner = hooks.NewRunner(hooksDir(), defaultHookTimeout)
+
+func hooksDir() string {
+	if dir := os.Getenv(hooksDirEnv); dir != "" {
+		return dir
+	}
+	return defaultHooksDir
+}
+
+type subcommand struct {
+	usage string
+	setup func(fs *flag.FlagSet) func(args []string)
+}
+
+var subcommands = map[string]subcommand{
+	"list":    {"list tasks", listCmd},
+	"archive": {"move done tasks to the archive", archiveCmd},
+	"restore": {"restore archived task by id", restoreCmd},
+	"export":  {"print tasks as json", exportCmd},
+	"ui":      {"interactive terminal interface", uiCmd},
+	"search":  {"full-text search in task names and descriptions", searchCmd},
+	"serve":   {"serve tasks over HTTP JSON API", serveCmd},
+	"board":   {"write tasks to a markdown board and sync edits back", boardCmd},
+
+	"push": {"create or update a note on the notes server for every task", pushCmd},
+	"pull": {"import notes from the notes server as tasks", pullCmd},
+
+	"completion": {"print shell completion script for bash, zsh or fish", completionCmd},
+}
 
-	var fList bool
-	flag.BoolVar(&fList, "list", false, "list tasks")
+// hiddenSubcommands are called by scripts and not offered to users.
+var hiddenSubcommands = map[string]subcommand{
+	completeCmdName: {"print completion candidates", completeCmd},
+}
 
-	var fGet string
-	flag.StringVar(&fGet, "get", "", "get task by id")
+func runSubcommand(name string, args []string) bool {
+	sc, ok := subcommands[name]
+	if !ok {
+		sc, ok = hiddenSubcommands[name]
+	}
+	if !ok {
+		return false
+	}
 
-	var fDel string
-	flag.StringVar(&fDel, "del", "", "delete task by id")
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	run := sc.setup(fs)
+	fs.Parse(args)
+	run(fs.Args())
 
-	var fDone string
-	flag.StringVar(&fDone, "done", "", "mark done task by id")
+	return true
+}
 
-	var fNew string
-	flag.StringVar(&fNew, "new", "", "create new task by \"<name>|<description>\"")
+func Run() {
+	if err := setTimezone(os.Getenv(tzEnv)); err != nil {
+		logger.Warn("invalid timezone", "env", tzEnv, "error", err)
+	}
+
+	if len(os.Args) > 1 && runSubcommand(os.Args[1], os.Args[2:]) {
+		return
+	}
+
+	daemonCfg := daemonFlags(flag.CommandLine)
+	applySettings := settingsFlags(flag.CommandLine)
+	o := rootFlags(flag.CommandLine)
 
 	flag.Parse()
 
+	if err := applySettings(); err != nil {
+		logger.Warn("invalid settings", "error", err)
+		return
+	}
+	cfg, err := daemonCfg()
+	if err != nil {
+		logger.Warn("invalid daemon settings", "error", err)
+		return
+	}
+
 	switch {
-	case fDaemonSrc != "":
-		daemon(fDaemonSrc)
-	case fList:
+	case cfg.enabled():
+		daemon(cfg, daemonCfg)
+	case o.list:
 		logger.Info("task", "tasks", listTasks())
-	case fGet != "":
-		if t, ok := task(fGet); ok {
+	case o.get != "":
+		if t, err := task(o.get); err == nil {
 			logger.Info("got task", "task", t)
 		} else {
-			logger.Warn("task not exists", "id", fGet)
+			logger.Warn("task get error", "error", err)
 		}
-	case fDel != "":
-		if err := deleteTask(fDel); err == nil {
-			logger.Info("task deleted", "id", fDel)
+	case o.del != "":
+		if err := deleteTask(o.del); err == nil {
+			logger.Info("task deleted", "id", o.del)
 		} else {
 			logger.Warn("task delete error", "error", err)
 		}
-	case fDone != "":
-		if err := markDoneTask(fDone); err == nil {
-			logger.Info("task marked done", "id", fDone)
+	case o.done != "":
+		if err := markDoneTask(o.done); err == nil {
+			logger.Info("task marked done", "id", o.done)
 		} else {
 			logger.Warn("task mark done error", "error", err)
 		}
-	case fNew != "":
-		rows := strings.SplitN(fNew, "|", 2)
+	case o.new != "":
+		due, err := parseDue(o.due)
+		if err != nil {
+			logger.Warn("invalid due time", "input", o.due, "error", err)
+			return
+		}
+		rows := strings.SplitN(o.new, "|", 2)
 		if len(rows) == 2 {
-			if id, err := newTask(rows[0], rows[1]); err == nil {
+			if id, err := newTask(rows[0], rows[1], due, parseTags(o.tags)); err == nil {
 				logger.Info("task created", "id", id)
 			} else {
 				logger.Warn("task create error", "error", err)
 			}
 		} else {
-			logger.Warn("invalid new task format", "input", fNew, "expected", "<name>|<description>")
+			logger.Warn("invalid new task format", "input", o.new, "expected", "<name>|<description>")
 		}
 	default:
 		logger.Warn("Empty call")
 	}
 }
+
+// rootFlags registers the flags of calls without a subcommand.
+func rootFlags(fs *flag.FlagSet) *rootOptions {
+	o := &rootOptions{}
+	fs.BoolVar(&o.list, "list", false, "list tasks")
+	fs.StringVar(&o.get, "get", "", "get task by id, id prefix or number")
+	fs.StringVar(&o.del, "del", "", "delete task by id, id prefix or number")
+	fs.StringVar(&o.done, "done", "", "mark done task by id, id prefix or number")
+	fs.StringVar(&o.new, "new", "", "create new task by \"<name>|<description>\"")
+	fs.StringVar(&o.due, "due", "", "due time for new task, e.g. \"tomorrow 17:00\" or \"in 3 days\"")
+	fs.StringVar(&o.tags, "tags", "", "comma separated tags for new task")
+	return o
+}
+
+type rootOptions struct {
+	list                           bool
+	get, del, done, new, due, tags string
+}
+
+// settingsFlags registers flags shared by all commands changing tasks and
+// returns a function applying them after parsing.
+func settingsFlags(fs *flag.FlagSet) func() error {
+	hooksDirFlag := fs.String("hooks", hooksDir(), "directory with hook executables")
+	hookTimeout := fs.Duration("hook-timeout", defaultHookTimeout, "max run time of a single hook")
+	tz := fs.String("tz", os.Getenv(tzEnv), "timezone for due time phrases (default local)")
+
+	return func() error {
+		if err := setTimezone(*tz); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", *tz, err)
+		}
+		hookRunner = hooks.NewRunner(*hooksDirFlag, *hookTimeout)
+		return nil
+	}
+}
+
+func parseTags(s string) []string {
+	tags := []string{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			tags = append(tags, part)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+func parseDurations(s string) ([]time.Duration, error) {
+	result := []time.Duration{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
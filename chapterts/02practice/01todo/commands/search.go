@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"todo/cli/db"
+	"todo/cli/search"
+)
+
+func searchCmd(fs *flag.FlagSet) func(args []string) {
+	archived := fs.Bool("archived", false, "include archived tasks")
+	limit := fs.Int("limit", 20, "max number of results")
+
+	return func(args []string) {
+		query := strings.Join(args, " ")
+		if strings.TrimSpace(query) == "" {
+			logger.Warn("invalid search call", "expected", "search <query>")
+			return
+		}
+
+		s := db.GetStorage()
+		short := s.ShortIDs()
+		open, close := highlightMarkers()
+
+		shown := 0
+		for _, r := range s.Search(query) {
+			if r.Archived && !*archived {
+				continue
+			}
+			if shown == *limit {
+				break
+			}
+			shown++
+
+			status := " "
+			if r.Task.Done {
+				status = "x"
+			}
+			line := fmt.Sprintf("[%s] %-5s %-13s %s", status, r.Task.Ref(), short[r.Task.ID.String()],
+				search.Highlight(r.Task.Name, query, open, close))
+			if r.Archived {
+				line += " (archived)"
+			}
+			fmt.Println(line)
+			if r.Task.Description != "" {
+				fmt.Println("      " + search.Highlight(r.Task.Description, query, open, close))
+			}
+		}
+
+		if shown == 0 {
+			logger.Info("no tasks found", "query", query)
+		}
+	}
+}
+
+func highlightMarkers() (string, string) {
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return "\x1b[1;33m", "\x1b[0m"
+	}
+	return "**", "**"
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os/signal"
+	"time"
+	"todo/cli/db"
+)
+
+// shutdownTimeout limits how long in-flight requests may finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+func serveCmd(fs *flag.FlagSet) func(args []string) {
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	daemonCfg := daemonFlags(fs)
+	applySettings := settingsFlags(fs)
+
+	return func(args []string) {
+		if err := applySettings(); err != nil {
+			logger.Warn("invalid settings", "error", err)
+			return
+		}
+		cfg, err := daemonCfg()
+		if err != nil {
+			logger.Warn("invalid daemon settings", "error", err)
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+		defer stop()
+
+		s := db.GetStorage()
+		health := instrument(s, cfg.healthMaxAge)
+		s.StartSaveEveryMinute(ctx)
+		if cfg.metricsAddr != "" {
+			serveMonitoring(ctx, cfg.metricsAddr, s, health)
+		}
+
+		daemonDone := make(chan struct{})
+		go func() {
+			defer close(daemonDone)
+			if cfg.enabled() {
+				runDaemon(ctx, cfg, daemonCfg, s)
+			}
+		}()
+
+		mux := apiMux(s)
+		handleMonitoring(mux, s, health)
+		server := &http.Server{Addr: *addr, Handler: mux}
+		serverDone := make(chan struct{})
+		go func() {
+			defer close(serverDone)
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				logger.Warn("API server shutdown", "error", err)
+			}
+		}()
+
+		logger.Info("API server started", "addr", *addr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			logger.Error("API server stopped", "error", err)
+			stop()
+		}
+
+		<-serverDone
+		<-daemonDone
+		finalSave(s)
+	}
+}
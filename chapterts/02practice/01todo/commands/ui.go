@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"flag"
+	"todo/cli/db"
+	"todo/cli/hooks"
+	"todo/cli/tui"
+)
+
+func uiCmd(fs *flag.FlagSet) func(args []string) {
+	applySettings := settingsFlags(fs)
+
+	return func(args []string) {
+		if err := applySettings(); err != nil {
+			logger.Warn("invalid settings", "error", err)
+			return
+		}
+
+		err := tui.Run(tui.Options{
+			Load: db.GetStorage,
+			Add: func(s *db.Storage, t *db.Task) error {
+				_, err := applyAdd(s, t, hooks.SourceCLI)
+				return err
+			},
+			MarkDone: func(s *db.Storage, id string) error {
+				_, err := applyMarkDone(s, id, hooks.SourceCLI)
+				return err
+			},
+			Delete: func(s *db.Storage, id string) error {
+				return applyDelete(s, id, hooks.SourceCLI)
+			},
+			ParseDue: parseDue,
+			ModTime:  db.StorageModTime,
+			Location: dueParser.Location(),
+		})
+		if err != nil {
+			logger.Warn("ui error", "error", err)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHour = 9
+	endOfHour   = 23
+	endOfMinute = 59
+)
+
+var errUnknown = errors.New("unknown date format")
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+type Interpretation struct {
+	Time    time.Time
+	Meaning string
+}
+
+type AmbiguousError struct {
+	Input           string
+	Interpretations []Interpretation
+}
+
+func (e *AmbiguousError) Error() string {
+	variants := make([]string, 0, len(e.Interpretations))
+	for _, i := range e.Interpretations {
+		variants = append(variants, fmt.Sprintf("%s (%s)", i.Time.Format(time.RFC3339), i.Meaning))
+	}
+	return fmt.Sprintf("ambiguous date %q: %s", e.Input, strings.Join(variants, ", "))
+}
+
+type Parser struct {
+	loc *time.Location
+	now func() time.Time
+}
+
+func NewParser(loc *time.Location) *Parser {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Parser{loc: loc, now: time.Now}
+}
+
+func (p *Parser) Location() *time.Location {
+	return p.loc
+}
+
+// Parse accepts RFC3339 timestamps and phrases like "tomorrow 17:00",
+// "next friday", "in 3 days" or "end of month" relative to the current
+// time in the parser location.
+func (p *Parser) Parse(input string) (time.Time, error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return time.Time{}, errors.New("empty date")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	now := p.now().In(p.loc)
+	variants, err := interpret(strings.Fields(strings.ToLower(s)), now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse date %q: %w", input, err)
+	}
+	if len(variants) > 1 {
+		return time.Time{}, &AmbiguousError{Input: input, Interpretations: variants}
+	}
+
+	return variants[0].Time, nil
+}
+
+type clock struct {
+	hour, minute int
+}
+
+func interpret(words []string, now time.Time) ([]Interpretation, error) {
+	words, at, err := splitClock(words)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(words) == 0 {
+		if at == nil {
+			return nil, errUnknown
+		}
+		t := atClock(now, *at)
+		if t.Before(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return []Interpretation{{t, "next " + t.Format("15:04")}}, nil
+	}
+
+	if exact, ok, err := parseExact(words, now); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		if at != nil {
+			return nil, errors.New("time of day is not allowed with relative hours or minutes")
+		}
+		return []Interpretation{exact}, nil
+	}
+
+	days, endOf, err := parseDay(words, now)
+	if err != nil {
+		return nil, err
+	}
+
+	c := clock{defaultHour, 0}
+	if endOf {
+		c = clock{endOfHour, endOfMinute}
+	}
+	if at != nil {
+		c = *at
+	}
+
+	for i := range days {
+		days[i].Time = atClock(days[i].Time, c)
+	}
+
+	return days, nil
+}
+
+func atClock(day time.Time, c clock) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, c.hour, c.minute, 0, 0, day.Location())
+}
+
+func splitClock(words []string) ([]string, *clock, error) {
+	n := len(words)
+	if n == 0 {
+		return words, nil, nil
+	}
+
+	if n >= 2 && (words[n-1] == "am" || words[n-1] == "pm") {
+		c, err := parseClock(words[n-2] + words[n-1])
+		if err != nil {
+			return nil, nil, err
+		}
+		return trimAt(words[:n-2]), c, nil
+	}
+
+	c, err := parseClock(words[n-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	if c == nil {
+		return words, nil, nil
+	}
+
+	return trimAt(words[:n-1]), c, nil
+}
+
+func trimAt(words []string) []string {
+	if len(words) > 0 && words[len(words)-1] == "at" {
+		return words[:len(words)-1]
+	}
+	return words
+}
+
+func parseClock(w string) (*clock, error) {
+	switch w {
+	case "noon":
+		return &clock{12, 0}, nil
+	case "midnight":
+		return &clock{0, 0}, nil
+	}
+
+	suffix := ""
+	if strings.HasSuffix(w, "am") || strings.HasSuffix(w, "pm") {
+		suffix = w[len(w)-2:]
+		w = w[:len(w)-2]
+	}
+
+	hs, ms, hasMinutes := strings.Cut(w, ":")
+	if !hasMinutes && suffix == "" {
+		return nil, nil
+	}
+
+	h, err := strconv.Atoi(hs)
+	if err != nil {
+		return nil, nil
+	}
+	m := 0
+	if hasMinutes {
+		if m, err = strconv.Atoi(ms); err != nil || len(ms) != 2 {
+			return nil, nil
+		}
+	}
+
+	switch suffix {
+	case "am", "pm":
+		if h < 1 || h > 12 {
+			return nil, fmt.Errorf("invalid hour %d%s", h, suffix)
+		}
+		h %= 12
+		if suffix == "pm" {
+			h += 12
+		}
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return nil, fmt.Errorf("invalid time %s%s", w, suffix)
+	}
+
+	return &clock{h, m}, nil
+}
+
+func parseExact(words []string, now time.Time) (Interpretation, bool, error) {
+	if len(words) == 1 && words[0] == "now" {
+		return Interpretation{now, "now"}, true, nil
+	}
+	if len(words) != 3 || words[0] != "in" {
+		return Interpretation{}, false, nil
+	}
+
+	var unit time.Duration
+	switch words[2] {
+	case "minute", "minutes", "min", "mins":
+		unit = time.Minute
+	case "hour", "hours", "h":
+		unit = time.Hour
+	default:
+		return Interpretation{}, false, nil
+	}
+
+	n, err := parseCount(words[1])
+	if err != nil {
+		return Interpretation{}, true, err
+	}
+
+	return Interpretation{now.Add(time.Duration(n) * unit), strings.Join(words, " ")}, true, nil
+}
+
+func parseCount(w string) (int, error) {
+	if w == "a" || w == "an" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(w)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid count %q", w)
+	}
+	return n, nil
+}
+
+func parseDay(words []string, now time.Time) ([]Interpretation, bool, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	one := func(t time.Time, meaning string) []Interpretation {
+		return []Interpretation{{t, meaning}}
+	}
+	phrase := strings.Join(words, " ")
+
+	switch phrase {
+	case "today":
+		return one(today, phrase), false, nil
+	case "tomorrow":
+		return one(today.AddDate(0, 0, 1), phrase), false, nil
+	case "yesterday":
+		return one(today.AddDate(0, 0, -1), phrase), false, nil
+	case "next week":
+		return one(today.AddDate(0, 0, 7), phrase), false, nil
+	case "next month":
+		return one(today.AddDate(0, 1, 0), phrase), false, nil
+	case "end of day", "eod":
+		return one(today, "end of day"), true, nil
+	case "end of week", "eow":
+		return one(today.AddDate(0, 0, (7-int(today.Weekday()))%7), "end of week"), true, nil
+	case "end of month", "eom":
+		return one(time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), "end of month"), true, nil
+	case "end of year", "eoy":
+		return one(time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, today.Location()), "end of year"), true, nil
+	}
+
+	if wd, ok := weekdays[words[len(words)-1]]; ok && len(words) <= 2 {
+		ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+		switch {
+		case len(words) == 2 && words[0] == "next":
+			if ahead == 0 {
+				ahead = 7
+			}
+			return one(today.AddDate(0, 0, ahead), phrase), false, nil
+		case len(words) == 2 && words[0] == "this":
+			return one(today.AddDate(0, 0, ahead), phrase), false, nil
+		case len(words) == 1 && ahead == 0:
+			return []Interpretation{
+				{today, "today"},
+				{today.AddDate(0, 0, 7), "in a week"},
+			}, false, nil
+		case len(words) == 1:
+			return one(today.AddDate(0, 0, ahead), phrase), false, nil
+		}
+	}
+
+	if len(words) == 3 && words[0] == "in" {
+		n, err := parseCount(words[1])
+		if err != nil {
+			return nil, false, err
+		}
+		switch words[2] {
+		case "day", "days":
+			return one(today.AddDate(0, 0, n), phrase), false, nil
+		case "week", "weeks":
+			return one(today.AddDate(0, 0, 7*n), phrase), false, nil
+		case "month", "months":
+			return one(today.AddDate(0, n, 0), phrase), false, nil
+		case "year", "years":
+			return one(today.AddDate(n, 0, 0), phrase), false, nil
+		}
+		return nil, false, fmt.Errorf("unknown unit %q", words[2])
+	}
+
+	if len(words) == 1 {
+		if t, err := time.ParseInLocation(time.DateOnly, words[0], now.Location()); err == nil {
+			return one(t, "date"), false, nil
+		}
+		if strings.Contains(words[0], "/") {
+			days, err := parseSlashDate(words[0], today)
+			return days, false, err
+		}
+	}
+
+	return nil, false, errUnknown
+}
+
+func parseSlashDate(w string, today time.Time) ([]Interpretation, error) {
+	parts := strings.Split(w, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, errUnknown
+	}
+
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errUnknown
+		}
+		nums[i] = n
+	}
+
+	year := today.Year()
+	if len(nums) == 3 {
+		year = nums[2]
+		if year < 100 {
+			year += 2000
+		}
+	}
+
+	variants := []Interpretation{}
+	add := func(month, day int, meaning string) {
+		if month < 1 || month > 12 || day < 1 {
+			return
+		}
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+		if t.Day() != day {
+			return
+		}
+		for _, v := range variants {
+			if v.Time.Equal(t) {
+				return
+			}
+		}
+		variants = append(variants, Interpretation{t, meaning})
+	}
+	add(nums[0], nums[1], "month/day")
+	add(nums[1], nums[0], "day/month")
+
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("invalid date %q", w)
+	}
+
+	return variants, nil
+}
@@ -0,0 +1,96 @@
+package dates
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testParser(t *testing.T) *Parser {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	p := NewParser(loc)
+	// Wednesday
+	p.now = func() time.Time { return time.Date(2025, time.April, 16, 14, 30, 0, 0, loc) }
+	return p
+}
+
+func TestParser_Parse(t *testing.T) {
+	p := testParser(t)
+	loc := p.Location()
+	date := func(m time.Month, d, h, min int) time.Time {
+		return time.Date(2025, m, d, h, min, 0, 0, loc)
+	}
+
+	var tests = []struct {
+		input    string
+		expected time.Time
+	}{
+		{"2025-04-18T10:30:00Z", time.Date(2025, time.April, 18, 10, 30, 0, 0, time.UTC)},
+		{"tomorrow 17:00", date(time.April, 17, 17, 0)},
+		{"Tomorrow at 5pm", date(time.April, 17, 17, 0)},
+		{"tomorrow 5:30 am", date(time.April, 17, 5, 30)},
+		{"today", date(time.April, 16, 9, 0)},
+		{"next friday", date(time.April, 18, 9, 0)},
+		{"next wednesday", date(time.April, 23, 9, 0)},
+		{"this wed noon", date(time.April, 16, 12, 0)},
+		{"friday 8:15", date(time.April, 18, 8, 15)},
+		{"in 3 days", date(time.April, 19, 9, 0)},
+		{"in a week at 10:00", date(time.April, 23, 10, 0)},
+		{"in 2 hours", date(time.April, 16, 16, 30)},
+		{"in 45 minutes", date(time.April, 16, 15, 15)},
+		{"end of month", date(time.April, 30, 23, 59)},
+		{"end of week", date(time.April, 20, 23, 59)},
+		{"eom 18:00", date(time.April, 30, 18, 0)},
+		{"2025-05-02", date(time.May, 2, 9, 0)},
+		{"2025-05-02 14:00", date(time.May, 2, 14, 0)},
+		{"25/12", date(time.December, 25, 9, 0)},
+		{"4/4/2025", date(time.April, 4, 9, 0)},
+		{"18:00", date(time.April, 16, 18, 0)},
+		{"9am", date(time.April, 17, 9, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := p.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse returned an error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParser_ParseAmbiguous(t *testing.T) {
+	p := testParser(t)
+
+	for _, input := range []string{"03/04", "wednesday"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := p.Parse(input)
+
+			var ambiguous *AmbiguousError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("expected AmbiguousError, got %v", err)
+			}
+			if len(ambiguous.Interpretations) != 2 {
+				t.Errorf("expected 2 interpretations, got %v", ambiguous.Interpretations)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalid(t *testing.T) {
+	p := testParser(t)
+
+	for _, input := range []string{"", "someday", "in many days", "31/31", "in 2 hours 17:00", "13pm"} {
+		t.Run(input, func(t *testing.T) {
+			if result, err := p.Parse(input); err == nil {
+				t.Errorf("expected error, got %v", result)
+			}
+		})
+	}
+}
@@ -1,16 +1,23 @@
 package db
 
 import (
+	"cmp"
 	"encoding/json"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	"todo/cli/search"
 
 	"github.com/google/uuid"
 	"github.com/spf13/afero"
 )
 
-const storageFp = "./storage.json"
+const (
+	storageFp = "./storage.json"
+	archiveFp = "./archive.json"
+)
 
 var appFs afero.Fs
 
@@ -19,11 +26,53 @@ func init() {
 }
 
 type Task struct {
-	ID          uuid.UUID `json:"id"`
-	Time        time.Time `json:"time"`
-	Done        bool      `json:"done"`
-	Name        string    `json:"name"`
-	Description string    `json:"desc"`
+	ID          uuid.UUID  `json:"id"`
+	Seq         int        `json:"seq,omitempty"`
+	Time        time.Time  `json:"time"`
+	Done        bool       `json:"done"`
+	DoneAt      *time.Time `json:"done_at,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"desc"`
+	Tags        []string   `json:"tags,omitempty"`
+	List        string     `json:"list,omitempty"`
+}
+
+func (t *Task) clone() *Task {
+	c := *t
+	if t.DoneAt != nil {
+		doneAt := *t.DoneAt
+		c.DoneAt = &doneAt
+	}
+	c.Tags = append([]string(nil), t.Tags...)
+	return &c
+}
+
+// Ref names t by its number, which counts tasks of its list, the way
+// ResolveID accepts it: "#3" in the default list and "work#3" in list work.
+func (t *Task) Ref() string {
+	return t.List + "#" + strconv.Itoa(t.Seq)
+}
+
+// CompareSeq orders tasks by list, then by their number within it.
+func CompareSeq(a, b *Task) int {
+	return cmp.Or(strings.Compare(a.List, b.List), cmp.Compare(a.Seq, b.Seq))
+}
+
+func (t *Task) document() search.Document {
+	return search.Document{ID: t.ID.String(), Name: t.Name, Description: t.Description}
+}
+
+// doneBefore reports whether t was completed before deadline. Tasks done
+// before DoneAt was recorded fall back to their time, and are never old
+// enough when they have none.
+func (t *Task) doneBefore(deadline time.Time) bool {
+	if !t.Done {
+		return false
+	}
+	if t.DoneAt == nil {
+		return !t.Time.IsZero() && t.Time.Before(deadline)
+	}
+	return t.DoneAt.Before(deadline)
 }
 
 type TaskBuilder struct {
@@ -32,6 +81,8 @@ type TaskBuilder struct {
 	time        time.Time
 	name        string
 	description string
+	tags        []string
+	list        string
 }
 
 func NewTaskBuilder(genId func() uuid.UUID) *TaskBuilder {
@@ -67,6 +118,16 @@ func (t *TaskBuilder) WithDescription(d string) *TaskBuilder {
 	return t
 }
 
+func (t *TaskBuilder) WithTags(tags ...string) *TaskBuilder {
+	t.tags = tags
+	return t
+}
+
+func (t *TaskBuilder) WithList(l string) *TaskBuilder {
+	t.list = l
+	return t
+}
+
 func (t *TaskBuilder) Build() *Task {
 	t.withId(t.genId())
 
@@ -75,11 +136,63 @@ func (t *TaskBuilder) Build() *Task {
 		Time:        t.time,
 		Name:        t.name,
 		Description: t.description,
+		Tags:        t.tags,
+		List:        t.list,
 	}
 }
 
-func getDataFromFs() (map[string]*Task, error) {
-	jsonFile, err := appFs.Open(storageFp)
+// HasTag reports whether t is tagged with tag, ignoring case.
+func (t *Task) HasTag(tag string) bool {
+	for _, v := range t.Tags {
+		if strings.EqualFold(v, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// storageFile is the format of storageFp. The last task number of every
+// list is kept, so numbers of deleted tasks aren't given again after a
+// restart. LastSeq is the one of the default list.
+type storageFile struct {
+	LastSeq  int              `json:"last_seq"`
+	ListSeqs map[string]int   `json:"list_seqs,omitempty"`
+	Tasks    map[string]*Task `json:"tasks"`
+}
+
+// getDataFromFs reads the active tasks and the last task number by list.
+// Files written before the numbers were kept hold only the tasks.
+func getDataFromFs() (map[string]*Task, map[string]int, error) {
+	byteValue, err := afero.ReadFile(appFs, storageFp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(byteValue, &members); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := members["tasks"]; !ok {
+		var d map[string]*Task
+		if err := json.Unmarshal(byteValue, &d); err != nil {
+			return nil, nil, err
+		}
+		return d, nil, nil
+	}
+
+	var f storageFile
+	if err := json.Unmarshal(byteValue, &f); err != nil {
+		return nil, nil, err
+	}
+	lastSeq := map[string]int{"": f.LastSeq}
+	for list, seq := range f.ListSeqs {
+		lastSeq[list] = seq
+	}
+	return f.Tasks, lastSeq, nil
+}
+
+func getDataFromFile(fp string) (map[string]*Task, error) {
+	jsonFile, err := appFs.Open(fp)
 	if err != nil {
 		return nil, err
 	}
@@ -96,13 +209,31 @@ func getDataFromFs() (map[string]*Task, error) {
 	return d, nil
 }
 
-func saveDataToFs(d map[string]*Task) error {
-	byteValue, err := json.MarshalIndent(d, "", "  ")
+func saveDataToFs(d map[string]*Task, lastSeq map[string]int) error {
+	f := storageFile{LastSeq: lastSeq[""], Tasks: d}
+	for list, seq := range lastSeq {
+		if list == "" {
+			continue
+		}
+		if f.ListSeqs == nil {
+			f.ListSeqs = map[string]int{}
+		}
+		f.ListSeqs[list] = seq
+	}
+	return writeJSONFile(storageFp, f)
+}
+
+func saveDataToFile(fp string, d map[string]*Task) error {
+	return writeJSONFile(fp, d)
+}
+
+func writeJSONFile(fp string, v any) error {
+	byteValue, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	jsonFile, err := appFs.OpenFile(storageFp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	jsonFile, err := appFs.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
@@ -112,3 +243,15 @@ func saveDataToFs(d map[string]*Task) error {
 
 	return nil
 }
+
+// StorageModTime reports the latest modification of the storage files, so
+// long running readers can notice changes made by other processes.
+func StorageModTime() time.Time {
+	var latest time.Time
+	for _, fp := range []string{storageFp, archiveFp} {
+		if info, err := appFs.Stat(fp); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
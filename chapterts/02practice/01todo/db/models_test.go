@@ -125,7 +125,7 @@ func TestGetDataFromFs_Success(t *testing.T) {
 	jsonData, _ := json.Marshal(testTasksData)
 	afero.WriteFile(fs, storageFp, jsonData, 0644)
 
-	result, err := getDataFromFs()
+	result, _, err := getDataFromFs()
 	if err != nil {
 		t.Errorf("GetDataFromFs returned an Error: %v", err)
 	}
@@ -139,7 +139,7 @@ func TestGetDataFromFs_FileNotExist(t *testing.T) {
 	_, teardown := setupMockFS()
 	defer teardown()
 
-	data, err := getDataFromFs()
+	data, _, err := getDataFromFs()
 
 	if err != nil && !os.IsNotExist(err) {
 		t.Errorf("GetDataFromFs returned an unexpected error for a non-existent file: %v", err)
@@ -155,7 +155,7 @@ func TestGetDataFromFs_InvalidJSON(t *testing.T) {
 
 	afero.WriteFile(fs, storageFp, []byte("this is not valid json"), 0644)
 
-	data, err := getDataFromFs()
+	data, _, err := getDataFromFs()
 
 	if err == nil {
 		t.Errorf("GetDataFromFs should have returned an error for invalid JSON")
@@ -169,7 +169,7 @@ func TestSaveDataToFs_Success(t *testing.T) {
 	fs, teardown := setupMockFS()
 	defer teardown()
 
-	err := saveDataToFs(testTasksData)
+	err := saveDataToFs(testTasksData, map[string]int{"": 7, "work": 2})
 	if err != nil {
 		t.Errorf("saveDataToFs returned an error: %v", err)
 	}
@@ -179,13 +179,21 @@ func TestSaveDataToFs_Success(t *testing.T) {
 		t.Fatalf("Failed to read data from mock file system: %v", err)
 	}
 
-	var readData map[string]*Task
+	var readData storageFile
 	err = json.Unmarshal(readDataBytes, &readData)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal data read from mock file system: %v", err)
 	}
 
-	if !reflect.DeepEqual(readData, testTasksData) {
-		t.Errorf("Saved data does not match the original data. Got: %v, Want: %v", readData, testTasksData)
+	if !reflect.DeepEqual(readData.Tasks, testTasksData) {
+		t.Errorf("Saved data does not match the original data. Got: %v, Want: %v", readData.Tasks, testTasksData)
+	}
+	if readData.LastSeq != 7 || readData.ListSeqs["work"] != 2 {
+		t.Errorf("expected last_seq 7 and work 2 to be saved, got %d %v", readData.LastSeq, readData.ListSeqs)
+	}
+
+	result, lastSeq, err := getDataFromFs()
+	if err != nil || !reflect.DeepEqual(lastSeq, map[string]int{"": 7, "work": 2}) || !reflect.DeepEqual(result, testTasksData) {
+		t.Errorf("getDataFromFs didn't read back saved data: %v, %v, %v", result, lastSeq, err)
 	}
 }
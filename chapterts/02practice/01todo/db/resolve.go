@@ -0,0 +1,162 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	minShortIDLen  = 8
+	minPrefixLen   = 4
+	maxCandidates  = 10
+	uuidHyphenated = 36
+)
+
+type NotFoundError struct {
+	Ref string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("task '%v' not exists", e.Ref)
+}
+
+type AmbiguousIDError struct {
+	Ref        string
+	Candidates []*Task
+}
+
+func (e *AmbiguousIDError) Error() string {
+	variants := make([]string, 0, len(e.Candidates))
+	for i, t := range e.Candidates {
+		if i == maxCandidates {
+			variants = append(variants, fmt.Sprintf("and %d more", len(e.Candidates)-maxCandidates))
+			break
+		}
+		variants = append(variants, fmt.Sprintf("%v (%s %s)", t.ID, t.Ref(), t.Name))
+	}
+	return fmt.Sprintf("ambiguous task id '%v', candidates: %s", e.Ref, strings.Join(variants, ", "))
+}
+
+// ResolveID finds the task referenced by full id, unique id prefix (at least
+// minPrefixLen chars) or sequence number, in both active and archived tasks.
+// Numbers are per list: "3" and "#3" name a task of the default list,
+// "work#3" one of list work, ignoring case.
+func (s *Storage) ResolveID(ref string) (string, error) {
+	sn := s.current.Load()
+
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if _, ok := sn.data[ref]; ok {
+		return ref, nil
+	}
+	if _, ok := sn.archive[ref]; ok {
+		return ref, nil
+	}
+
+	list, num := "", ref
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		list, num = ref[:i], ref[i+1:]
+	}
+	seq, err := strconv.Atoi(num)
+	isSeq := err == nil && seq > 0 && !strings.HasPrefix(num, "0")
+	isPrefix := len(ref) >= minPrefixLen && len(ref) < uuidHyphenated && num == ref
+
+	candidates := []*Task{}
+	for _, tasks := range []map[string]*Task{sn.data, sn.archive} {
+		for id, t := range tasks {
+			if (isSeq && t.Seq == seq && strings.EqualFold(t.List, list)) || (isPrefix && matchesPrefix(id, ref)) {
+				candidates = append(candidates, t.clone())
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", &NotFoundError{ref}
+	case 1:
+		return candidates[0].ID.String(), nil
+	}
+
+	slices.SortFunc(candidates, CompareSeq)
+	return "", &AmbiguousIDError{ref, candidates}
+}
+
+func matchesPrefix(id, prefix string) bool {
+	if strings.HasPrefix(id, prefix) {
+		return true
+	}
+	return !strings.Contains(prefix, "-") && strings.HasPrefix(strings.ReplaceAll(id, "-", ""), prefix)
+}
+
+// ShortIDs returns the shortest unique prefix of every task id, never
+// shorter than minShortIDLen.
+func (s *Storage) ShortIDs() map[string]string {
+	sn := s.current.Load()
+
+	ids := make([]string, 0, len(sn.data)+len(sn.archive))
+	for _, tasks := range []map[string]*Task{sn.data, sn.archive} {
+		for id := range tasks {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	short := make(map[string]string, len(ids))
+	for i, id := range ids {
+		n := minShortIDLen
+		if i > 0 {
+			n = max(n, commonPrefixLen(id, ids[i-1])+1)
+		}
+		if i < len(ids)-1 {
+			n = max(n, commonPrefixLen(id, ids[i+1])+1)
+		}
+		short[id] = id[:min(n, len(id))]
+	}
+
+	return short
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// assignMissingSeq numbers tasks stored before sequence numbers existed.
+// It runs before the snapshot is published.
+func (sn *snapshot) assignMissingSeq() {
+	missing := []*Task{}
+	for _, tasks := range []map[string]*Task{sn.data, sn.archive} {
+		for _, t := range tasks {
+			sn.lastSeq[t.List] = max(sn.lastSeq[t.List], t.Seq)
+			if t.Seq == 0 {
+				missing = append(missing, t)
+			}
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].ID.String() < missing[j].ID.String() })
+
+	for _, t := range missing {
+		t.Seq = sn.nextSeq(t.List)
+	}
+}
+
+// nextSeq gives the next number of list. The snapshot must not be
+// published yet.
+func (sn *snapshot) nextSeq(list string) int {
+	sn.lastSeq[list]++
+	return sn.lastSeq[list]
+}
+
+// keepSeq returns the number of old when it stays in list, or a new number
+// of list when it moves.
+func (sn *snapshot) keepSeq(old *Task, list string) int {
+	if old.List == list {
+		return old.Seq
+	}
+	return sn.nextSeq(list)
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func fixedIdTask(id string) *Task {
+	return NewTaskBuilder(func() uuid.UUID { return uuid.MustParse(id) }).WithName(id[:4]).Build()
+}
+
+func resolveTestStorage(t *testing.T) *Storage {
+	s := newStorage(nil, nil)
+	for _, id := range []string{
+		"01964483-01b5-779f-9c6f-b2496503591d",
+		"01964483-02c1-779f-9c6f-b2496503591d",
+		"11111111-0000-4000-8000-000000000000",
+	} {
+		if err := s.AddTask(fixedIdTask(id)); err != nil {
+			t.Fatalf("AddTask returned an error: %v", err)
+		}
+	}
+	return s
+}
+
+func TestStorage_ResolveID(t *testing.T) {
+	s := resolveTestStorage(t)
+
+	var tests = []struct {
+		ref      string
+		expected string
+	}{
+		{"01964483-01b5-779f-9c6f-b2496503591d", "01964483-01b5-779f-9c6f-b2496503591d"},
+		{"01964483-01", "01964483-01b5-779f-9c6f-b2496503591d"},
+		{"0196448302C", "01964483-02c1-779f-9c6f-b2496503591d"},
+		{"2", "01964483-02c1-779f-9c6f-b2496503591d"},
+		{"1111", "11111111-0000-4000-8000-000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			id, err := s.ResolveID(tt.ref)
+			if err != nil {
+				t.Fatalf("ResolveID returned an error: %v", err)
+			}
+			if id != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, id)
+			}
+		})
+	}
+}
+
+func TestStorage_ResolveIDErrors(t *testing.T) {
+	s := resolveTestStorage(t)
+	s.AddTask(fixedIdTask("31000000-0000-4000-8000-000000000000"))
+
+	var ambiguous *AmbiguousIDError
+	if _, err := s.ResolveID("0196"); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("expected ambiguous prefix with 2 candidates, got %v", err)
+	}
+
+	// "3100" is both a sequence number candidate and an id prefix
+	s.current.Load().data["31000000-0000-4000-8000-000000000000"].Seq = 3100
+	if _, err := s.ResolveID("3100"); err != nil {
+		t.Errorf("same task by seq and prefix must not be ambiguous, got %v", err)
+	}
+	s.current.Load().data["11111111-0000-4000-8000-000000000000"].Seq = 3100
+	if _, err := s.ResolveID("3100"); !errors.As(err, &ambiguous) {
+		t.Errorf("expected ambiguous seq and prefix, got %v", err)
+	}
+
+	var notFound *NotFoundError
+	for _, ref := range []string{"99", "abc", "ffff", "01"} {
+		if _, err := s.ResolveID(ref); !errors.As(err, &notFound) {
+			t.Errorf("expected not found for %q, got %v", ref, err)
+		}
+	}
+}
+
+func TestStorage_ShortIDs(t *testing.T) {
+	s := resolveTestStorage(t)
+	short := s.ShortIDs()
+
+	expected := map[string]string{
+		"01964483-01b5-779f-9c6f-b2496503591d": "01964483-01",
+		"01964483-02c1-779f-9c6f-b2496503591d": "01964483-02",
+		"11111111-0000-4000-8000-000000000000": "11111111",
+	}
+	for id, want := range expected {
+		if short[id] != want {
+			t.Errorf("expected short id %v for %v, got %v", want, id, short[id])
+		}
+		if resolved, err := s.ResolveID(short[id]); err != nil || resolved != id {
+			t.Errorf("short id %v does not resolve back: %v, %v", short[id], resolved, err)
+		}
+	}
+}
+
+func TestNewStorage_AssignsMissingSeq(t *testing.T) {
+	a := fixedIdTask("01964483-01b5-779f-9c6f-b2496503591d")
+	b := fixedIdTask("01964483-02c1-779f-9c6f-b2496503591d")
+	c := fixedIdTask("11111111-0000-4000-8000-000000000000")
+	c.Seq = 5
+
+	s := newStorage(map[string]*Task{a.ID.String(): a, c.ID.String(): c}, map[string]*Task{b.ID.String(): b})
+	if a.Seq != 6 || b.Seq != 7 || c.Seq != 5 {
+		t.Errorf("unexpected seq assignment: a=%d b=%d c=%d", a.Seq, b.Seq, c.Seq)
+	}
+
+	d := fixedIdTask("21111111-0000-4000-8000-000000000000")
+	s.AddTask(d)
+	if d.Seq != 8 {
+		t.Errorf("expected new task seq 8, got %d", d.Seq)
+	}
+}
+
+func TestGetStorage_KeepsSeqOfDeletedTasks(t *testing.T) {
+	_, teardown := setupMockFS()
+	defer teardown()
+
+	s := resolveTestStorage(t)
+	if err := s.DeleteTask("11111111-0000-4000-8000-000000000000"); err != nil {
+		t.Fatalf("DeleteTask returned an error: %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+
+	task := fixedIdTask("21111111-0000-4000-8000-000000000000")
+	if err := GetStorage().AddTask(task); err != nil {
+		t.Fatalf("AddTask returned an error: %v", err)
+	}
+	if task.Seq != 4 {
+		t.Errorf("expected seq 4 after restart, #3 belonged to a deleted task, got %d", task.Seq)
+	}
+}
+
+func TestStorage_SeqPerList(t *testing.T) {
+	s := newStorage(nil, nil)
+	invoice := fixedIdTask("01964483-01b5-779f-9c6f-b2496503591d")
+	review := fixedIdTask("01964483-02c1-779f-9c6f-b2496503591d")
+	review.List = "GitHub"
+	milk := fixedIdTask("11111111-0000-4000-8000-000000000000")
+	for _, task := range []*Task{invoice, review, milk} {
+		if err := s.AddTask(task); err != nil {
+			t.Fatalf("AddTask returned an error: %v", err)
+		}
+	}
+	if invoice.Seq != 1 || review.Seq != 1 || milk.Seq != 2 {
+		t.Fatalf("expected numbers per list, got %d %d %d", invoice.Seq, review.Seq, milk.Seq)
+	}
+	if review.Ref() != "GitHub#1" || milk.Ref() != "#2" {
+		t.Errorf("unexpected refs %q %q", review.Ref(), milk.Ref())
+	}
+
+	var tests = []struct {
+		ref      string
+		expected string
+	}{
+		{"1", invoice.ID.String()},
+		{"#1", invoice.ID.String()},
+		{"github#1", review.ID.String()},
+		{"GitHub#1", review.ID.String()},
+	}
+	for _, tt := range tests {
+		if id, err := s.ResolveID(tt.ref); err != nil || id != tt.expected {
+			t.Errorf("%q: expected %v, got %v, %v", tt.ref, tt.expected, id, err)
+		}
+	}
+	var notFound *NotFoundError
+	for _, ref := range []string{"github#2", "work#1", "github#"} {
+		if _, err := s.ResolveID(ref); !errors.As(err, &notFound) {
+			t.Errorf("expected not found for %q, got %v", ref, err)
+		}
+	}
+
+	if err := s.UpdateTaskFunc(milk.ID.String(), func(t *Task) error {
+		t.List = "GitHub"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if moved, _ := s.GetTask(milk.ID.String()); moved.Ref() != "GitHub#2" {
+		t.Errorf("moved task must be numbered in its new list, got %q", moved.Ref())
+	}
+}
@@ -1,50 +1,116 @@
 package db
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
+	"todo/cli/search"
 
 	"log/slog"
 )
 
 var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
 
+// snapshot is an immutable state of the storage. Tasks referenced from a
+// published snapshot are never modified, writers replace them with copies.
+type snapshot struct {
+	data    map[string]*Task
+	archive map[string]*Task
+	// lastSeq is the last task number given in every list.
+	lastSeq map[string]int
+}
+
+func (sn *snapshot) clone() *snapshot {
+	next := &snapshot{
+		data:    make(map[string]*Task, len(sn.data)+1),
+		archive: make(map[string]*Task, len(sn.archive)),
+		lastSeq: maps.Clone(sn.lastSeq),
+	}
+	for id, t := range sn.data {
+		next.data[id] = t
+	}
+	for id, t := range sn.archive {
+		next.archive[id] = t
+	}
+	return next
+}
+
+// Storage keeps tasks in copy-on-write snapshots: readers load the current
+// snapshot without locking, writers are serialized and publish a new one.
 type Storage struct {
-	data   map[string]*Task
-	locker chan struct{}
+	current atomic.Pointer[snapshot]
+	writer  sync.Mutex
+	saver   sync.Mutex
+	index   *search.Index
+	onSave  func(took time.Duration, err error)
 }
 
 func GetStorage() *Storage {
-	data, err := getDataFromFs()
+	data, lastSeq, err := getDataFromFs()
 	if err != nil {
 		logger.Info("Storage not loaded from file system. New one will be created.", "error", err)
 	}
 
-	return newStorage(data)
+	archive, err := getDataFromFile(archiveFp)
+	if err != nil {
+		logger.Info("Archive not loaded from file system. New one will be created.", "error", err)
+	}
+
+	return newStorageAt(data, archive, lastSeq)
 }
 
-func newStorage(data map[string]*Task) *Storage {
+func newStorage(data, archive map[string]*Task) *Storage {
+	return newStorageAt(data, archive, nil)
+}
+
+// newStorageAt continues task numbers of every list after lastSeq, or after
+// the highest stored number when that's larger.
+func newStorageAt(data, archive map[string]*Task, lastSeq map[string]int) *Storage {
 	if data == nil {
 		data = map[string]*Task{}
 	}
+	if archive == nil {
+		archive = map[string]*Task{}
+	}
+	if lastSeq == nil {
+		lastSeq = map[string]int{}
+	}
+
+	sn := &snapshot{data: data, archive: archive, lastSeq: lastSeq}
+	sn.assignMissingSeq()
 
-	return &Storage{
-		data:   data,
-		locker: make(chan struct{}, 1),
+	s := &Storage{index: search.NewIndex()}
+	for _, tasks := range []map[string]*Task{sn.data, sn.archive} {
+		for _, t := range tasks {
+			s.index.Put(t.document())
+		}
 	}
+	s.current.Store(sn)
+
+	return s
 }
 
-func (s *Storage) StartSaveEveryMinute() {
-	s.startPeriodicalSave(time.Minute)
+// StartSaveEveryMinute saves in the background until ctx is done. Callers
+// should Save once more after that to keep the latest changes.
+func (s *Storage) StartSaveEveryMinute(ctx context.Context) {
+	s.startPeriodicalSave(ctx, time.Minute)
 }
 
-func (s *Storage) startPeriodicalSave(period time.Duration) {
+func (s *Storage) startPeriodicalSave(ctx context.Context, period time.Duration) {
 	ticker := time.NewTicker(period)
 
 	go func() {
+		defer ticker.Stop()
 		for {
-			<-ticker.C
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
 			if err := s.Save(); err != nil {
 				logger.Error("Failed save tasks to Fs", "error", err)
 			} else {
@@ -54,72 +120,230 @@ func (s *Storage) startPeriodicalSave(period time.Duration) {
 	}()
 }
 
+// Save writes a consistent snapshot of active and archived tasks. It doesn't
+// block readers or writers, concurrent saves are serialized.
 func (s *Storage) Save() error {
-	return saveDataToFs(s.data)
+	s.saver.Lock()
+	defer s.saver.Unlock()
+
+	start := time.Now()
+	err := s.save(s.current.Load())
+	if s.onSave != nil {
+		s.onSave(time.Since(start), err)
+	}
+	return err
 }
 
-func (s *Storage) ListTasks() []*Task {
-	to_defer := s.borrowSpace()
-	defer to_defer()
+func (s *Storage) save(sn *snapshot) error {
+	if err := saveDataToFs(sn.data, sn.lastSeq); err != nil {
+		return err
+	}
+	return saveDataToFile(archiveFp, sn.archive)
+}
+
+// OnSave sets fn to be called after every save attempt with its duration
+// and result.
+func (s *Storage) OnSave(fn func(took time.Duration, err error)) {
+	s.saver.Lock()
+	defer s.saver.Unlock()
+
+	s.onSave = fn
+}
+
+// update applies fn to a private copy of the current snapshot and publishes
+// the copy when fn succeeds.
+func (s *Storage) update(fn func(next *snapshot) error) error {
+	s.writer.Lock()
+	defer s.writer.Unlock()
 
-	v := make([]*Task, 0, len(s.data))
-	for _, val := range s.data {
-		v = append(v, val)
+	next := s.current.Load().clone()
+	if err := fn(next); err != nil {
+		return err
 	}
+	s.current.Store(next)
+
+	return nil
+}
 
+func listCopies(tasks map[string]*Task) []*Task {
+	v := make([]*Task, 0, len(tasks))
+	for _, val := range tasks {
+		v = append(v, val.clone())
+	}
 	return v
 }
 
-func (s *Storage) GetTask(id string) (*Task, bool) {
-	to_defer := s.borrowSpace()
-	defer to_defer()
+func getCopy(tasks map[string]*Task, id string) (*Task, bool) {
+	t, ok := tasks[id]
+	if !ok {
+		return nil, false
+	}
+	return t.clone(), true
+}
 
-	t, ok := s.data[id]
-	return t, ok
+func (s *Storage) ListTasks() []*Task {
+	return listCopies(s.current.Load().data)
 }
 
-func (s *Storage) borrowSpace() func() {
-	s.locker <- struct{}{}
-	return func() { <-s.locker }
+func (s *Storage) GetTask(id string) (*Task, bool) {
+	return getCopy(s.current.Load().data, id)
 }
 
 func (s *Storage) AddTask(t *Task) error {
-	to_defer := s.borrowSpace()
-	defer to_defer()
-
-	tid := t.ID.String()
-	if _, exists := s.data[tid]; exists {
-		return fmt.Errorf("task with id '%v' already exists", tid)
-	}
+	return s.update(func(next *snapshot) error {
+		tid := t.ID.String()
+		if _, exists := next.data[tid]; exists {
+			return fmt.Errorf("task with id '%v' already exists", tid)
+		}
+		if _, exists := next.archive[tid]; exists {
+			return fmt.Errorf("task with id '%v' already exists in archive", tid)
+		}
 
-	s.data[tid] = t
+		t.Seq = next.nextSeq(t.List)
+		next.data[tid] = t.clone()
+		s.index.Put(t.document())
 
-	return nil
+		return nil
+	})
 }
 
 func (s *Storage) DeleteTask(id string) error {
-	to_defer := s.borrowSpace()
-	defer to_defer()
+	return s.update(func(next *snapshot) error {
+		if _, exists := next.data[id]; exists {
+			delete(next.data, id)
+			s.index.Remove(id)
+			return nil
+		}
+
+		return fmt.Errorf("task with id '%v' not exists", id)
+	})
+}
+
+// UpdateTask replaces the stored task with the same id, keeping its number
+// unless it moves to another list.
+func (s *Storage) UpdateTask(t *Task) error {
+	return s.update(func(next *snapshot) error {
+		tid := t.ID.String()
+		old, exists := next.data[tid]
+		if !exists {
+			return fmt.Errorf("task with id '%v' not exists", tid)
+		}
+
+		t.Seq = next.keepSeq(old, t.List)
+		next.data[tid] = t.clone()
+		s.index.Put(t.document())
+
+		return nil
+	})
+}
+
+// UpdateTaskFunc changes the stored task with fn under the writer lock, so
+// changes made meanwhile, like marking it done, aren't overwritten. fn gets
+// a copy and nothing is stored when it fails.
+func (s *Storage) UpdateTaskFunc(id string, fn func(t *Task) error) error {
+	return s.update(func(next *snapshot) error {
+		old, exists := next.data[id]
+		if !exists {
+			return fmt.Errorf("task with id '%v' not exists", id)
+		}
+
+		t := old.clone()
+		if err := fn(t); err != nil {
+			return err
+		}
+		t.ID, t.Seq = old.ID, next.keepSeq(old, t.List)
+		next.data[id] = t.clone()
+		s.index.Put(t.document())
 
-	if _, exists := s.data[id]; exists {
-		delete(s.data, id)
 		return nil
+	})
+}
+
+// MarkDone completes the task and returns a copy of it as stored.
+func (s *Storage) MarkDone(id string) (*Task, error) {
+	var done *Task
+	err := s.update(func(next *snapshot) error {
+		if t, exists := next.data[id]; exists {
+			if t.Done {
+				return fmt.Errorf("task with id '%v' already marked done", id)
+			}
+			now := time.Now()
+			done = t.clone()
+			done.Done = true
+			done.DoneAt = &now
+			next.data[id] = done
+			return nil
+		}
+
+		return fmt.Errorf("task with id '%v' not exists", id)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return done.clone(), nil
+}
+
+func (s *Storage) ListArchivedTasks() []*Task {
+	return listCopies(s.current.Load().archive)
+}
 
-	return fmt.Errorf("task with id '%v' not exists", id)
+func (s *Storage) GetArchivedTask(id string) (*Task, bool) {
+	return getCopy(s.current.Load().archive, id)
 }
 
-func (s *Storage) MarkDone(id string) error {
-	to_defer := s.borrowSpace()
-	defer to_defer()
+// ArchiveDone moves tasks completed more than olderThan ago to the archive
+// and returns ids of the moved tasks.
+func (s *Storage) ArchiveDone(olderThan time.Duration) []string {
+	deadline := time.Now().Add(-olderThan)
+	moved := []string{}
 
-	if t, exists := s.data[id]; exists {
-		if t.Done {
-			return fmt.Errorf("task with id '%v' already marked done", id)
+	s.update(func(next *snapshot) error {
+		for id, t := range next.data {
+			if t.doneBefore(deadline) {
+				next.archive[id] = t
+				delete(next.data, id)
+				moved = append(moved, id)
+			}
 		}
-		t.Done = true
 		return nil
+	})
+
+	return moved
+}
+
+func (s *Storage) RestoreTask(id string) error {
+	return s.update(func(next *snapshot) error {
+		t, exists := next.archive[id]
+		if !exists {
+			return fmt.Errorf("task with id '%v' not exists in archive", id)
+		}
+
+		next.data[id] = t
+		delete(next.archive, id)
+
+		return nil
+	})
+}
+
+type SearchResult struct {
+	Task     *Task
+	Archived bool
+	Score    float64
+}
+
+// Search ranks active and archived tasks by relevance of their name and
+// description to the query.
+func (s *Storage) Search(query string) []SearchResult {
+	sn := s.current.Load()
+
+	results := []SearchResult{}
+	for _, r := range s.index.Search(query) {
+		if t, ok := sn.data[r.ID]; ok {
+			results = append(results, SearchResult{t.clone(), false, r.Score})
+		} else if t, ok := sn.archive[r.ID]; ok {
+			results = append(results, SearchResult{t.clone(), true, r.Score})
+		}
 	}
 
-	return fmt.Errorf("task with id '%v' not exists", id)
+	return results
 }
@@ -0,0 +1,420 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestStorage_ArchiveDone(t *testing.T) {
+	_, teardown := setupMockFS()
+	defer teardown()
+
+	old := NewTaskBuilder(UuidIdGenerator).WithName("old").Build()
+	fresh := NewTaskBuilder(UuidIdGenerator).WithName("fresh").Build()
+	open := NewTaskBuilder(UuidIdGenerator).WithName("open").Build()
+
+	doneAt := time.Now().Add(-48 * time.Hour)
+	old.Done = true
+	old.DoneAt = &doneAt
+	// Done before done_at was recorded, without a time either.
+	legacy := &Task{ID: UuidIdGenerator(), Name: "legacy", Done: true}
+
+	s := newStorage(nil, nil)
+	for _, task := range []*Task{old, fresh, open, legacy} {
+		if err := s.AddTask(task); err != nil {
+			t.Fatalf("AddTask returned an error: %v", err)
+		}
+	}
+	s.MarkDone(fresh.ID.String())
+
+	ids := s.ArchiveDone(24 * time.Hour)
+	if len(ids) != 1 || ids[0] != old.ID.String() {
+		t.Fatalf("expected only %v archived, got %v", old.ID, ids)
+	}
+	if _, ok := s.GetTask(old.ID.String()); ok {
+		t.Errorf("archived task still listed as active")
+	}
+	if _, ok := s.GetArchivedTask(old.ID.String()); !ok {
+		t.Errorf("archived task not found in archive")
+	}
+	if len(s.ListTasks()) != 3 {
+		t.Errorf("expected 3 active tasks, got %d", len(s.ListTasks()))
+	}
+
+	if err := s.AddTask(old); err == nil {
+		t.Errorf("AddTask should reject id already present in archive")
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+	archive, err := getDataFromFile(archiveFp)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	if _, ok := archive[old.ID.String()]; !ok {
+		t.Errorf("archive file does not contain archived task")
+	}
+}
+
+func TestStorage_RestoreTask(t *testing.T) {
+	task := NewTaskBuilder(UuidIdGenerator).WithName("archived").Build()
+	s := newStorage(nil, map[string]*Task{task.ID.String(): task})
+
+	if err := s.RestoreTask(task.ID.String()); err != nil {
+		t.Fatalf("RestoreTask returned an error: %v", err)
+	}
+	if _, ok := s.GetTask(task.ID.String()); !ok {
+		t.Errorf("restored task not found in active tasks")
+	}
+	if len(s.ListArchivedTasks()) != 0 {
+		t.Errorf("restored task still present in archive")
+	}
+	if err := s.RestoreTask(task.ID.String()); err == nil {
+		t.Errorf("RestoreTask should fail for task not in archive")
+	}
+}
+
+func TestStorage_ReturnsCopies(t *testing.T) {
+	task := NewTaskBuilder(UuidIdGenerator).WithName("original").Build()
+	s := newStorage(nil, nil)
+	s.AddTask(task)
+	task.Name = "changed by caller"
+
+	got, _ := s.GetTask(task.ID.String())
+	if got.Name != "original" {
+		t.Errorf("storage must keep its own copy of added task, got %q", got.Name)
+	}
+
+	got.Name = "changed"
+	s.ListTasks()[0].Name = "changed"
+	if again, _ := s.GetTask(task.ID.String()); again.Name != "original" {
+		t.Errorf("returned tasks must be copies, got %q", again.Name)
+	}
+
+	done, err := s.MarkDone(task.ID.String())
+	if err != nil || !done.Done || done.DoneAt == nil {
+		t.Fatalf("MarkDone must return the done task, got %+v, %v", done, err)
+	}
+	if got.Done {
+		t.Errorf("previously returned task must not change after MarkDone")
+	}
+	done.Name = "changed"
+	if again, _ := s.GetTask(task.ID.String()); again.Name != "original" {
+		t.Errorf("MarkDone must return a copy, got %q", again.Name)
+	}
+	if _, err := s.MarkDone(task.ID.String()); err == nil {
+		t.Errorf("expected error marking a done task again")
+	}
+}
+
+func TestStorage_UpdateTaskFunc(t *testing.T) {
+	task := NewTaskBuilder(UuidIdGenerator).WithName("original").Build()
+	s := newStorage(nil, nil)
+	s.AddTask(task)
+	id := task.ID.String()
+	stale, _ := s.GetTask(id)
+
+	s.MarkDone(id)
+	err := s.UpdateTaskFunc(id, func(t *Task) error {
+		t.Name = "renamed"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateTaskFunc returned an error: %v", err)
+	}
+	got, _ := s.GetTask(id)
+	if got.Name != "renamed" || !got.Done || got.Seq != stale.Seq {
+		t.Errorf("expected renamed task still done with its number, got %+v", got)
+	}
+
+	rejected := errors.New("rejected")
+	err = s.UpdateTaskFunc(id, func(t *Task) error {
+		t.Name = "discarded"
+		return rejected
+	})
+	if !errors.Is(err, rejected) {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	if got, _ := s.GetTask(id); got.Name != "renamed" {
+		t.Errorf("failed update must not be stored, got %q", got.Name)
+	}
+	if err := s.UpdateTaskFunc("missing", func(*Task) error { return nil }); err == nil {
+		t.Errorf("UpdateTaskFunc should fail for missing task")
+	}
+}
+
+func TestStorage_ConcurrentAccess(t *testing.T) {
+	_, teardown := setupMockFS()
+	defer teardown()
+
+	const writers, perWriter = 4, 50
+	s := newStorage(nil, nil)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, task := range s.ListTasks() {
+					task.Name = "mutated by reader"
+					if got, ok := s.GetTask(task.ID.String()); ok && got.Name == "mutated by reader" {
+						t.Errorf("reader mutation leaked into storage")
+					}
+				}
+				s.ShortIDs()
+				s.ResolveID("1")
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := s.Save(); err != nil {
+				t.Errorf("Save returned an error: %v", err)
+			}
+		}
+	}()
+
+	var writersWg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		writersWg.Add(1)
+		go func() {
+			defer writersWg.Done()
+			for j := 0; j < perWriter; j++ {
+				task := NewTaskBuilder(UuidIdGenerator).WithName("task").Build()
+				if err := s.AddTask(task); err != nil {
+					t.Errorf("AddTask returned an error: %v", err)
+				}
+				if j%2 == 0 {
+					s.MarkDone(task.ID.String())
+				}
+				if j%5 == 0 {
+					s.DeleteTask(task.ID.String())
+				}
+			}
+			s.ArchiveDone(0)
+		}()
+	}
+
+	writersWg.Wait()
+	close(stop)
+	wg.Wait()
+
+	total := len(s.ListTasks()) + len(s.ListArchivedTasks())
+	if expected := writers * perWriter * 4 / 5; total != expected {
+		t.Errorf("expected %d tasks, got %d", expected, total)
+	}
+
+	seqs := map[int]bool{}
+	for _, task := range append(s.ListTasks(), s.ListArchivedTasks()...) {
+		if seqs[task.Seq] {
+			t.Errorf("duplicate seq %d", task.Seq)
+		}
+		seqs[task.Seq] = true
+	}
+}
+
+func TestStorage_SaveConsistentSnapshot(t *testing.T) {
+	_, teardown := setupMockFS()
+	defer teardown()
+
+	s := newStorage(nil, nil)
+	ids := []string{}
+	for i := 0; i < 20; i++ {
+		task := NewTaskBuilder(UuidIdGenerator).Build()
+		task.Done = true
+		s.AddTask(task)
+		ids = append(ids, task.ID.String())
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s.ArchiveDone(-time.Hour)
+			for _, id := range ids {
+				s.RestoreTask(id)
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		if err := s.Save(); err != nil {
+			t.Fatalf("Save returned an error: %v", err)
+		}
+		data, _, _ := getDataFromFs()
+		archive, _ := getDataFromFile(archiveFp)
+		if len(data)+len(archive) != len(ids) {
+			t.Fatalf("saved inconsistent state: %d active + %d archived, want %d", len(data), len(archive), len(ids))
+		}
+	}
+
+	close(stop)
+	<-done
+}
+
+// chanLockedTasks reproduces the previous Storage locking, where every call
+// was serialized through a one-slot channel, to compare read throughput.
+// It returns copies as well, otherwise readers would race with writers.
+type chanLockedTasks struct {
+	data   map[string]*Task
+	locker chan struct{}
+}
+
+func (c *chanLockedTasks) get(id string) (*Task, bool) {
+	c.locker <- struct{}{}
+	defer func() { <-c.locker }()
+	t, ok := c.data[id]
+	if !ok {
+		return nil, false
+	}
+	return t.clone(), true
+}
+
+func (c *chanLockedTasks) add(t *Task) {
+	c.locker <- struct{}{}
+	defer func() { <-c.locker }()
+	c.data[t.ID.String()] = t
+}
+
+func benchmarkReadsWithWriter(b *testing.B, get func(string) (*Task, bool), add func(*Task)) {
+	ids := []string{}
+	for i := 0; i < 1000; i++ {
+		task := NewTaskBuilder(UuidIdGenerator).Build()
+		add(task)
+		ids = append(ids, task.ID.String())
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				add(NewTaskBuilder(UuidIdGenerator).Build())
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			get(ids[i%len(ids)])
+			i++
+		}
+	})
+}
+
+func BenchmarkStorage_ParallelGet(b *testing.B) {
+	b.Run("snapshot", func(b *testing.B) {
+		s := newStorage(nil, nil)
+		benchmarkReadsWithWriter(b, s.GetTask, func(t *Task) { s.AddTask(t) })
+	})
+	b.Run("channel_lock", func(b *testing.B) {
+		c := &chanLockedTasks{data: map[string]*Task{}, locker: make(chan struct{}, 1)}
+		benchmarkReadsWithWriter(b, c.get, c.add)
+	})
+}
+
+func BenchmarkStorage_ListTasks(b *testing.B) {
+	s := newStorage(nil, nil)
+	for i := 0; i < 1000; i++ {
+		s.AddTask(NewTaskBuilder(UuidIdGenerator).Build())
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.ListTasks()
+		}
+	})
+}
+
+func TestStorage_Search(t *testing.T) {
+	invoice := NewTaskBuilder(UuidIdGenerator).WithName("Send invoice").WithDescription("client ACME").Build()
+	archived := NewTaskBuilder(UuidIdGenerator).WithName("Old invoice").Build()
+	s := newStorage(nil, map[string]*Task{archived.ID.String(): archived})
+	s.AddTask(invoice)
+
+	results := s.Search("invoice")
+	if len(results) != 2 {
+		t.Fatalf("expected active and archived results, got %v", results)
+	}
+	for _, r := range results {
+		if r.Archived != (r.Task.ID == archived.ID) {
+			t.Errorf("wrong archived flag for %v", r.Task.Name)
+		}
+	}
+
+	edited := *invoice
+	edited.Name = "Send reminder"
+	s.UpdateTask(&edited)
+	if results := s.Search("invoice client"); len(results) != 0 {
+		t.Errorf("index not updated after edit, got %v", results)
+	}
+	if results := s.Search("remind"); len(results) != 1 {
+		t.Errorf("edited task not found by new name, got %v", results)
+	}
+
+	s.DeleteTask(invoice.ID.String())
+	if results := s.Search("reminder"); len(results) != 0 {
+		t.Errorf("deleted task still found, got %v", results)
+	}
+}
+
+func TestStorage_OnSave(t *testing.T) {
+	fs, teardown := setupMockFS()
+	defer teardown()
+
+	s := newStorage(nil, nil)
+	var calls []error
+	s.OnSave(func(took time.Duration, err error) {
+		if took < 0 {
+			t.Errorf("negative save duration %v", took)
+		}
+		calls = append(calls, err)
+	})
+
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	appFs = afero.NewReadOnlyFs(fs)
+	if err := s.Save(); err == nil {
+		t.Fatal("expected save error on read-only fs")
+	}
+
+	if len(calls) != 2 || calls[0] != nil || calls[1] == nil {
+		t.Errorf("unexpected OnSave calls %v", calls)
+	}
+}
@@ -5,6 +5,5 @@ go 1.24.2
 require (
 	github.com/google/uuid v1.6.0
 	github.com/spf13/afero v1.14.0
+	golang.org/x/text v0.23.0
 )
-
-require golang.org/x/text v0.23.0 // indirect
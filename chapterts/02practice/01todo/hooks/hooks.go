@@ -0,0 +1,170 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"todo/cli/db"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+type Event string
+
+const (
+	PreAdd    Event = "pre-add"
+	OnAdd     Event = "on-add"
+	OnDone    Event = "on-done"
+	OnDelete  Event = "on-delete"
+	OnOverdue Event = "on-overdue"
+	OnRemind  Event = "on-remind"
+)
+
+const (
+	SourceCLI    = "cli"
+	SourceDaemon = "daemon"
+	SourceAPI    = "api"
+	SourceBoard  = "board"
+	SourceNotes  = "notes"
+)
+
+type Runner struct {
+	dir     string
+	timeout time.Duration
+}
+
+func NewRunner(dir string, timeout time.Duration) *Runner {
+	return &Runner{dir: dir, timeout: timeout}
+}
+
+// Run executes every hook registered for the event. Failures are only
+// logged: post-event hooks can't undo the operation that triggered them.
+func (r *Runner) Run(event Event, source string, t *db.Task, env ...string) {
+	if err := r.RunChecked(event, source, t, env...); err != nil {
+		logger.Error("hook failed", "event", event, "id", t.ID, "error", err)
+	}
+}
+
+// RunChecked is like Run but reports the first failure to the caller.
+func (r *Runner) RunChecked(event Event, source string, t *db.Task, env ...string) error {
+	var first error
+	for _, fp := range r.find(event) {
+		if _, err := r.exec(fp, event, source, t, env); err != nil && first == nil {
+			first = fmt.Errorf("hook %s: %w", filepath.Base(fp), err)
+		}
+	}
+	return first
+}
+
+// PreAdd passes the task through pre-add hooks. A hook rejects the task by
+// exiting with non-zero status and modifies it by printing task JSON.
+func (r *Runner) PreAdd(source string, t *db.Task) (*db.Task, error) {
+	for _, fp := range r.find(PreAdd) {
+		out, err := r.exec(fp, PreAdd, source, t, nil)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s rejected task: %w", filepath.Base(fp), err)
+		}
+		if len(bytes.TrimSpace(out)) == 0 {
+			continue
+		}
+
+		modified := *t
+		if err := json.Unmarshal(out, &modified); err != nil {
+			return nil, fmt.Errorf("hook %s returned invalid task: %w", filepath.Base(fp), err)
+		}
+		modified.ID = t.ID
+		t = &modified
+	}
+
+	return t, nil
+}
+
+// isHookOf reports whether the file name is a hook of event: the event name
+// itself, or followed by "-" or "." like pre-add-10 or on-done.sh.
+func isHookOf(name string, event Event) bool {
+	rest, ok := strings.CutPrefix(name, string(event))
+	return ok && (rest == "" || rest[0] == '-' || rest[0] == '.')
+}
+
+func (r *Runner) find(event Event) []string {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed read hooks dir", "dir", r.dir, "error", err)
+		}
+		return nil
+	}
+
+	found := []string{}
+	for _, e := range entries {
+		if e.IsDir() || !isHookOf(e.Name(), event) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		found = append(found, filepath.Join(r.dir, e.Name()))
+	}
+	sort.Strings(found)
+
+	return found
+}
+
+func (r *Runner) exec(fp string, event Event, source string, t *db.Task, env []string) ([]byte, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, fp)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"TODO_EVENT="+string(event),
+		"TODO_SOURCE="+source,
+		"TODO_TASK_ID="+t.ID.String(),
+		"TODO_EVENT_TIME="+time.Now().Format(time.RFC3339),
+	)
+	cmd.Env = append(cmd.Env, env...)
+
+	started := time.Now()
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", r.timeout)
+	}
+
+	logger.Info("hook executed",
+		"hook", fp,
+		"event", event,
+		"source", source,
+		"id", t.ID,
+		"duration", time.Since(started),
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+		"error", err,
+	)
+
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
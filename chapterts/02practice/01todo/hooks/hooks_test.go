@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+func writeHook(t *testing.T, dir, name, script string) {
+	fp := filepath.Join(dir, name)
+	if err := os.WriteFile(fp, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+}
+
+func testTask() *db.Task {
+	return db.NewTaskBuilder(db.UuidIdGenerator).WithName("Buy milk").Build()
+}
+
+func TestRunner_PreAddModifies(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, "pre-add-10", `sed 's/Buy milk/Buy oat milk/'`)
+	writeHook(t, dir, "pre-add-20", `cat >/dev/null`)
+
+	task := testTask()
+	result, err := NewRunner(dir, time.Second).PreAdd(SourceCLI, task)
+	if err != nil {
+		t.Fatalf("PreAdd returned an error: %v", err)
+	}
+	if result.Name != "Buy oat milk" {
+		t.Errorf("expected modified name, got %q", result.Name)
+	}
+	if result.ID != task.ID {
+		t.Errorf("hook must not change task id")
+	}
+}
+
+func TestRunner_PreAddRejects(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, "pre-add", `echo "names must be uppercase" >&2; exit 1`)
+
+	_, err := NewRunner(dir, time.Second).PreAdd(SourceDaemon, testTask())
+	if err == nil || !strings.Contains(err.Error(), "names must be uppercase") {
+		t.Errorf("expected rejection with hook message, got %v", err)
+	}
+}
+
+func TestRunner_PreAddTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, "pre-add", `sleep 5`)
+
+	_, err := NewRunner(dir, 50*time.Millisecond).PreAdd(SourceCLI, testTask())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestRunner_RunPassesEventEnv(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	writeHook(t, dir, "on-done.sh", `echo "$TODO_EVENT $TODO_SOURCE $TODO_TASK_ID" > `+out+"\ncat >> "+out)
+	writeHook(t, dir, "on-delete", `exit 1`)
+	if err := os.WriteFile(filepath.Join(dir, "on-done-disabled"), []byte("exit 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	task := testTask()
+	NewRunner(dir, time.Second).Run(OnDone, SourceDaemon, task)
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	lines := strings.SplitN(string(data), "\n", 2)
+	if lines[0] != "on-done daemon "+task.ID.String() {
+		t.Errorf("unexpected hook env: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `"name":"Buy milk"`) {
+		t.Errorf("hook stdin does not contain task json: %q", lines[1])
+	}
+}
+
+func TestRunner_MissingDir(t *testing.T) {
+	task := testTask()
+	result, err := NewRunner(filepath.Join(t.TempDir(), "missing"), time.Second).PreAdd(SourceCLI, task)
+	if err != nil || result != task {
+		t.Errorf("expected task unchanged without hooks dir, got %v, %v", result, err)
+	}
+}
+
+func TestRunner_FindMatchesWholeEventName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"on-add", "on-add-10", "on-add.sh", "on-added-backup.sh", "on-addition"} {
+		writeHook(t, dir, name, "true")
+	}
+
+	found := NewRunner(dir, time.Second).find(OnAdd)
+	var names []string
+	for _, fp := range found {
+		names = append(names, filepath.Base(fp))
+	}
+	if strings.Join(names, ",") != "on-add,on-add-10,on-add.sh" {
+		t.Errorf("expected only hooks of on-add, got %v", names)
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	ops := r.NewCounter("ops_total", "Processed operations.", "op")
+	size := r.NewGauge("queue_files", "Files waiting.")
+	latency := r.NewHistogram("save_seconds", "Save latency.", []float64{0.1, 0.01})
+
+	ops.Inc("new")
+	ops.Inc("new")
+	ops.Add(1, `mark"x`)
+	size.Set(3)
+	latency.Observe(0.005)
+	latency.Observe(0.05)
+	latency.Observe(2)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP ops_total Processed operations.
+# TYPE ops_total counter
+ops_total{op="mark\"x"} 1
+ops_total{op="new"} 2
+# HELP queue_files Files waiting.
+# TYPE queue_files gauge
+queue_files 3
+# HELP save_seconds Save latency.
+# TYPE save_seconds histogram
+save_seconds_bucket{le="0.01"} 1
+save_seconds_bucket{le="0.1"} 2
+save_seconds_bucket{le="+Inf"} 3
+save_seconds_sum 2.055
+save_seconds_count 3
+`
+	if b.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("ops_total", "Processed operations.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "ops_total 1\n") {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("tasks", "Tasks.")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on duplicate name")
+		}
+	}()
+	r.NewCounter("tasks", "Tasks.")
+}
+
+func TestCounter_Concurrent(t *testing.T) {
+	r := NewRegistry()
+	ops := r.NewCounter("ops_total", "Processed operations.", "op")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ops.Inc("new")
+				r.WriteText(&strings.Builder{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := ops.Value("new"); v != 800 {
+		t.Errorf("expected 800, got %v", v)
+	}
+}
@@ -0,0 +1,124 @@
+package notesync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Note is a note resource of the notes API server.
+type Note struct {
+	ID          string     `json:"id,omitempty"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	Created     *time.Time `json:"created,omitempty"`
+	AuthorID    *string    `json:"author_id"`
+}
+
+var ErrNoteNotFound = errors.New("note not found")
+
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("notes server responded with %d: %s", e.Status, e.Body)
+}
+
+// Client talks to the /note endpoints of the notes API server,
+// authenticated as the author token was issued for.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// listPageSize is the largest page the notes server returns.
+const listPageSize = 1000
+
+// List returns all notes, following next_cursor until the last page.
+func (c *Client) List(ctx context.Context) ([]Note, error) {
+	var notes []Note
+	query := url.Values{"limit": {strconv.Itoa(listPageSize)}}
+	for {
+		var resp struct {
+			Results    []Note  `json:"results"`
+			NextCursor *string `json:"next_cursor"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/note?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		notes = append(notes, resp.Results...)
+		if resp.NextCursor == nil || *resp.NextCursor == "" {
+			return notes, nil
+		}
+		query.Set("cursor", *resp.NextCursor)
+	}
+}
+
+func (c *Client) Create(ctx context.Context, n Note) (Note, error) {
+	var created Note
+	err := c.do(ctx, http.MethodPost, "/note", n, &created)
+	return created, err
+}
+
+// Update replaces the note with n.ID. It returns ErrNoteNotFound when the
+// note was deleted on the server.
+func (c *Client) Update(ctx context.Context, n Note) (Note, error) {
+	var updated Note
+	err := c.do(ctx, http.MethodPut, "/note/"+n.ID, n, &updated)
+	return updated, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, target any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNoteNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{resp.StatusCode, strings.TrimSpace(string(msg))}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
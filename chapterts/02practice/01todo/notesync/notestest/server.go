@@ -0,0 +1,216 @@
+// Package notestest provides an in-memory fake of the notes API server's
+// /note endpoints for tests.
+package notestest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"todo/cli/notesync"
+
+	"github.com/google/uuid"
+)
+
+// Page sizes of the notes server's list endpoints.
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+type Server struct {
+	*httptest.Server
+	// Token is the bearer token every request must carry.
+	Token string
+
+	mu    sync.Mutex
+	notes map[string]notesync.Note
+	// Requests counts handled requests by method.
+	requests map[string]int
+}
+
+// NewServer starts a fake notes server accepting requests authenticated
+// with token, callers should Close it.
+func NewServer(token string) *Server {
+	s := &Server{Token: token, notes: map[string]notesync.Note{}, requests: map[string]int{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /note", s.list)
+	mux.HandleFunc("POST /note", s.create)
+	mux.HandleFunc("GET /note/{id}", s.get)
+	mux.HandleFunc("PUT /note/{id}", s.update)
+	mux.HandleFunc("DELETE /note/{id}", s.delete)
+	s.Server = httptest.NewServer(s.count(s.authenticate(mux)))
+
+	return s
+}
+
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.Method]++
+		s.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate answers 401 like the notes server unless the request has
+// the bearer token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.Token {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="notes"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Notes returns stored notes ordered by id, which follows creation order.
+func (s *Server) Notes() []notesync.Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notes := make([]notesync.Note, 0, len(s.notes))
+	for _, n := range s.notes {
+		notes = append(notes, n)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes
+}
+
+// Put stores n as if it was created by another client and returns its id.
+func (s *Server) Put(n notesync.Note) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n.ID = uuid.Must(uuid.NewV7()).String()
+	if n.Created == nil {
+		now := time.Now()
+		n.Created = &now
+	}
+	s.notes[n.ID] = n
+	return n.ID
+}
+
+// Delete removes a note as if it was deleted by another client.
+func (s *Server) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.notes, id)
+}
+
+func (s *Server) Requests(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[method]
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func decode(w http.ResponseWriter, r *http.Request) (notesync.Note, bool) {
+	var n notesync.Note
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil || n.Name == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad_request"})
+		return n, false
+	}
+	return n, true
+}
+
+// list pages notes like the notes server: limit defaults to
+// DefaultListLimit and is capped at MaxListLimit, cursor is the
+// next_cursor of the previous page.
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultListLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad_request"})
+			return
+		}
+		limit = min(n, MaxListLimit)
+	}
+
+	notes := s.Notes()
+	total := len(notes)
+	if after := r.URL.Query().Get("cursor"); after != "" {
+		i := sort.Search(len(notes), func(i int) bool { return notes[i].ID > after })
+		notes = notes[i:]
+	}
+	var next *string
+	if len(notes) > limit {
+		notes = notes[:limit]
+		next = &notes[limit-1].ID
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": notes, "next_cursor": next, "total": total})
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	n, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	n.ID = s.Put(n)
+
+	s.mu.Lock()
+	n = s.notes[n.ID]
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	n, ok := s.notes[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request) {
+	n, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	old, exists := s.notes[id]
+	if exists {
+		n.ID, n.Created = id, old.Created
+		s.notes[id] = n
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	_, exists := s.notes[r.PathValue("id")]
+	delete(s.notes, r.PathValue("id"))
+	s.mu.Unlock()
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
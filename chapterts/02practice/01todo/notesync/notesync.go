@@ -0,0 +1,193 @@
+// Package notesync mirrors tasks as notes of the notes API server and
+// imports notes as tasks. A mapping file links tasks to their notes, so
+// later pushes update notes instead of creating duplicates.
+package notesync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"todo/cli/db"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+type entry struct {
+	NoteID string `json:"note_id"`
+	// Hash of the last pushed note, unchanged tasks aren't pushed again.
+	Hash string `json:"hash"`
+}
+
+type mapping struct {
+	Tasks map[string]entry `json:"tasks"`
+}
+
+func loadMapping(fp string) (*mapping, error) {
+	m := &mapping{Tasks: map[string]entry{}}
+
+	data, err := os.ReadFile(fp)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Tasks == nil {
+		m.Tasks = map[string]entry{}
+	}
+	return m, nil
+}
+
+func (m *mapping) save(fp string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, data, 0o644)
+}
+
+func (m *mapping) noteIDs() map[string]bool {
+	ids := map[string]bool{}
+	for _, e := range m.Tasks {
+		ids[e.NoteID] = true
+	}
+	return ids
+}
+
+type Syncer struct {
+	Client *Client
+	// MappingFp is the file linking task ids to note ids.
+	MappingFp string
+	// AuthorID is set as author of created notes when not empty.
+	AuthorID string
+}
+
+type Result struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+func (s *Syncer) note(t *db.Task) Note {
+	desc := t.Description
+	n := Note{Name: t.Name, Description: &desc}
+	if s.AuthorID != "" {
+		author := s.AuthorID
+		n.AuthorID = &author
+	}
+	return n
+}
+
+func hash(n Note) string {
+	data, _ := json.Marshal(n)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Push creates a note for every task without one and updates notes of tasks
+// changed since the last push. Notes deleted on the server are created
+// again, so Push lists them first. Failures of single tasks are logged and
+// counted, the mapping is saved in any case.
+func (s *Syncer) Push(ctx context.Context, tasks []*db.Task) (Result, error) {
+	var res Result
+
+	m, err := loadMapping(s.MappingFp)
+	if err != nil {
+		return res, err
+	}
+	notes, err := s.Client.List(ctx)
+	if err != nil {
+		return res, err
+	}
+	existing := map[string]bool{}
+	for _, n := range notes {
+		existing[n.ID] = true
+	}
+
+	for _, t := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+
+		id := t.ID.String()
+		n := s.note(t)
+		h := hash(n)
+
+		e, mapped := m.Tasks[id]
+		mapped = mapped && existing[e.NoteID]
+		if mapped && e.Hash == h {
+			res.Unchanged++
+			continue
+		}
+
+		var pushed Note
+		if mapped {
+			n.ID = e.NoteID
+			pushed, err = s.Client.Update(ctx, n)
+			if errors.Is(err, ErrNoteNotFound) {
+				n.ID = ""
+				mapped = false
+				pushed, err = s.Client.Create(ctx, n)
+			}
+		} else {
+			pushed, err = s.Client.Create(ctx, n)
+		}
+		if err != nil {
+			logger.Warn("Failed push task", "id", id, "error", err)
+			res.Failed++
+			continue
+		}
+
+		m.Tasks[id] = entry{NoteID: pushed.ID, Hash: h}
+		if mapped {
+			res.Updated++
+		} else {
+			res.Created++
+		}
+	}
+
+	return res, m.save(s.MappingFp)
+}
+
+// Pull calls add for every note not linked to a task yet and links the
+// returned task to the note.
+func (s *Syncer) Pull(ctx context.Context, add func(Note) (*db.Task, error)) (Result, error) {
+	var res Result
+
+	m, err := loadMapping(s.MappingFp)
+	if err != nil {
+		return res, err
+	}
+	notes, err := s.Client.List(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	linked := m.noteIDs()
+	for _, n := range notes {
+		if linked[n.ID] {
+			res.Unchanged++
+			continue
+		}
+
+		t, err := add(n)
+		if err != nil {
+			logger.Warn("Failed import note", "id", n.ID, "error", err)
+			res.Failed++
+			continue
+		}
+
+		m.Tasks[t.ID.String()] = entry{NoteID: n.ID, Hash: hash(s.note(t))}
+		res.Created++
+	}
+
+	return res, m.save(s.MappingFp)
+}
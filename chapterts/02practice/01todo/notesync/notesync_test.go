@@ -0,0 +1,139 @@
+package notesync_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"todo/cli/db"
+	"todo/cli/notesync"
+	"todo/cli/notesync/notestest"
+)
+
+func setupSyncer(t *testing.T) (*notesync.Syncer, *notestest.Server) {
+	server := notestest.NewServer("nt_test")
+	t.Cleanup(server.Close)
+
+	return &notesync.Syncer{
+		Client:    notesync.NewClient(server.URL+"/", server.Token),
+		MappingFp: filepath.Join(t.TempDir(), "notes_sync.json"),
+		AuthorID:  "01964483-01b5-779f-9c6f-b2496503591d",
+	}, server
+}
+
+func TestSyncer_Push(t *testing.T) {
+	syncer, server := setupSyncer(t)
+	ctx := context.Background()
+
+	invoice := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Send invoice").WithDescription("client").Build()
+	rent := db.NewTaskBuilder(db.UuidIdGenerator).WithName("Pay rent").Build()
+
+	res, err := syncer.Push(ctx, []*db.Task{invoice, rent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (notesync.Result{Created: 2}) {
+		t.Errorf("unexpected first push %+v", res)
+	}
+	notes := server.Notes()
+	if len(notes) != 2 || notes[0].Name != "Send invoice" || *notes[0].Description != "client" || *notes[0].AuthorID != syncer.AuthorID {
+		t.Fatalf("unexpected notes %+v", notes)
+	}
+
+	requests := server.Requests("POST") + server.Requests("PUT")
+	res, _ = syncer.Push(ctx, []*db.Task{invoice, rent})
+	if res != (notesync.Result{Unchanged: 2}) || server.Requests("POST")+server.Requests("PUT") != requests {
+		t.Errorf("unchanged tasks must not be pushed again: %+v", res)
+	}
+
+	invoice.Name = "Send invoices"
+	server.Delete(notes[1].ID)
+	res, _ = syncer.Push(ctx, []*db.Task{invoice, rent})
+	if res != (notesync.Result{Created: 1, Updated: 1}) {
+		t.Errorf("expected update of edited task and new note for deleted one, got %+v", res)
+	}
+
+	notes = server.Notes()
+	if len(notes) != 2 || notes[0].Name != "Send invoices" || notes[1].Name != "Pay rent" {
+		t.Errorf("unexpected notes after edit %+v", notes)
+	}
+}
+
+func TestSyncer_PushFailure(t *testing.T) {
+	syncer, _ := setupSyncer(t)
+	invalid := db.NewTaskBuilder(db.UuidIdGenerator).Build()
+	valid := db.NewTaskBuilder(db.UuidIdGenerator).WithName("valid").Build()
+
+	res, err := syncer.Push(context.Background(), []*db.Task{invalid, valid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (notesync.Result{Created: 1, Failed: 1}) {
+		t.Errorf("expected one failed task, got %+v", res)
+	}
+}
+
+func TestSyncer_Pull(t *testing.T) {
+	syncer, server := setupSyncer(t)
+	ctx := context.Background()
+
+	task := db.NewTaskBuilder(db.UuidIdGenerator).WithName("From todo").Build()
+	syncer.Push(ctx, []*db.Task{task})
+	desc := "from notes"
+	server.Put(notesync.Note{Name: "From notes", Description: &desc})
+
+	imported := []*db.Task{}
+	add := func(n notesync.Note) (*db.Task, error) {
+		t := db.NewTaskBuilder(db.UuidIdGenerator).WithName(n.Name).WithDescription(*n.Description).Build()
+		imported = append(imported, t)
+		return t, nil
+	}
+
+	res, err := syncer.Pull(ctx, add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (notesync.Result{Created: 1, Unchanged: 1}) || len(imported) != 1 || imported[0].Name != "From notes" {
+		t.Fatalf("expected only the new note imported, got %+v %v", res, imported)
+	}
+
+	if res, _ := syncer.Pull(ctx, add); res.Created != 0 {
+		t.Errorf("imported notes must not be imported again, got %+v", res)
+	}
+	posts := server.Requests("POST")
+	if res, _ := syncer.Push(ctx, append(imported, task)); res != (notesync.Result{Unchanged: 2}) || server.Requests("POST") != posts {
+		t.Errorf("imported tasks must not be pushed back as new notes, got %+v", res)
+	}
+}
+
+func TestClient_ListFollowsPages(t *testing.T) {
+	syncer, server := setupSyncer(t)
+	for range notestest.MaxListLimit + 5 {
+		server.Put(notesync.Note{Name: "note"})
+	}
+
+	notes, err := syncer.Client.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != notestest.MaxListLimit+5 {
+		t.Errorf("expected every page, got %d notes", len(notes))
+	}
+	if server.Requests("GET") != 2 {
+		t.Errorf("expected 2 page requests, got %d", server.Requests("GET"))
+	}
+}
+
+func TestClient_SendsToken(t *testing.T) {
+	_, server := setupSyncer(t)
+
+	_, err := notesync.NewClient(server.URL, "nt_wrong").List(context.Background())
+	var status *notesync.StatusError
+	if !errors.As(err, &status) || status.Status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong token, got %v", err)
+	}
+	if _, err := notesync.NewClient(server.URL, server.Token).List(context.Background()); err != nil {
+		t.Errorf("expected token to be accepted, got %v", err)
+	}
+}
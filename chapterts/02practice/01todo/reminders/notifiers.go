@@ -0,0 +1,109 @@
+package reminders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+	"todo/cli/hooks"
+)
+
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(r Reminder) error {
+	if r.Overdue {
+		logger.Warn("Task overdue", "id", r.Task.ID, "name", r.Task.Name, "due", r.Task.Time)
+	} else {
+		logger.Warn("Task due soon", "id", r.Task.ID, "name", r.Task.Name, "due", r.Task.Time, "in", r.Task.Time.Sub(r.FiredAt).Round(time.Second))
+	}
+	return nil
+}
+
+func (LogNotifier) String() string {
+	return "log"
+}
+
+// HookNotifier runs on-remind hooks for approaching tasks. Overdue
+// reminders are left to OverdueHookNotifier, which doesn't depend on the
+// thresholds.
+type HookNotifier struct {
+	Runner *hooks.Runner
+}
+
+func (n HookNotifier) Notify(r Reminder) error {
+	if r.Overdue {
+		return nil
+	}
+	return n.Runner.RunChecked(hooks.OnRemind, hooks.SourceDaemon, r.Task, "TODO_REMIND_THRESHOLD="+r.Threshold.String())
+}
+
+func (HookNotifier) String() string {
+	return "hook"
+}
+
+// OverdueHookNotifier runs on-overdue hooks, it is meant for
+// Scheduler.WithOverdue.
+type OverdueHookNotifier struct {
+	Runner *hooks.Runner
+}
+
+func (n OverdueHookNotifier) Notify(r Reminder) error {
+	return n.Runner.RunChecked(hooks.OnOverdue, hooks.SourceDaemon, r.Task, "TODO_REMIND_THRESHOLD="+r.Threshold.String())
+}
+
+func (OverdueHookNotifier) String() string {
+	return "overdue hook"
+}
+
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Notify(r Reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %v", resp.Status)
+	}
+
+	return nil
+}
+
+func (n *WebhookNotifier) String() string {
+	return "webhook"
+}
+
+type OutboxNotifier struct {
+	Dir string
+}
+
+func (n OutboxNotifier) Notify(r Reminder) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("remind_%s_%s.json", r.Task.ID, strconv.FormatInt(int64(r.Threshold/time.Second), 10))
+	return writeFileAtomic(filepath.Join(n.Dir, name), data)
+}
+
+func (OutboxNotifier) String() string {
+	return "outbox"
+}
@@ -0,0 +1,164 @@
+package reminders
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"todo/cli/db"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+type Reminder struct {
+	Task      *db.Task      `json:"task"`
+	Threshold time.Duration `json:"threshold"`
+	Overdue   bool          `json:"overdue"`
+	FiredAt   time.Time     `json:"fired_at"`
+}
+
+type Notifier interface {
+	Notify(r Reminder) error
+}
+
+type taskState struct {
+	Due   time.Time       `json:"due"`
+	Fired []time.Duration `json:"fired"`
+	// Overdue is set once overdue notifiers were told about the task.
+	Overdue bool `json:"overdue,omitempty"`
+}
+
+func (ts *taskState) fired(threshold time.Duration) bool {
+	for _, f := range ts.Fired {
+		if f == threshold {
+			return true
+		}
+	}
+	return false
+}
+
+type Scheduler struct {
+	thresholds []time.Duration
+	notifiers  []Notifier
+	overdue    []Notifier
+	stateFp    string
+
+	mu    sync.Mutex
+	state map[string]*taskState
+}
+
+// NewScheduler creates a scheduler firing reminders when a task is due
+// within one of the thresholds; a zero threshold means the task is overdue.
+// Fired reminders are stored in stateFp so they are not repeated after restart.
+func NewScheduler(thresholds []time.Duration, notifiers []Notifier, stateFp string) *Scheduler {
+	sorted := append([]time.Duration(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+	s := &Scheduler{
+		thresholds: sorted,
+		notifiers:  notifiers,
+		stateFp:    stateFp,
+		state:      map[string]*taskState{},
+	}
+	if err := s.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error("Failed load reminders state", "fp", stateFp, "error", err)
+	}
+
+	return s
+}
+
+// WithOverdue adds notifiers told once when a task becomes overdue, whatever
+// the thresholds are.
+func (s *Scheduler) WithOverdue(notifiers ...Notifier) *Scheduler {
+	s.overdue = append(s.overdue, notifiers...)
+	return s
+}
+
+// Check fires reminders for every threshold passed since the last check.
+// When several thresholds passed at once only the most urgent one fires.
+func (s *Scheduler) Check(tasks []*db.Task, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := 0
+	seen := map[string]bool{}
+	for _, t := range tasks {
+		if t.Done || t.Time.IsZero() {
+			continue
+		}
+
+		id := t.ID.String()
+		seen[id] = true
+		ts, ok := s.state[id]
+		if !ok || !ts.Due.Equal(t.Time) {
+			ts = &taskState{Due: t.Time}
+			s.state[id] = ts
+		}
+
+		var due []time.Duration
+		for _, th := range s.thresholds {
+			if !now.Before(t.Time.Add(-th)) && !ts.fired(th) {
+				due = append(due, th)
+			}
+		}
+		if len(due) > 0 {
+			th := due[len(due)-1]
+			s.notify(s.notifiers, Reminder{Task: t, Threshold: th, Overdue: th == 0, FiredAt: now})
+			ts.Fired = append(ts.Fired, due...)
+			counter++
+		}
+
+		if len(s.overdue) > 0 && !now.Before(t.Time) && !ts.Overdue {
+			s.notify(s.overdue, Reminder{Task: t, Overdue: true, FiredAt: now})
+			ts.Overdue = true
+			counter++
+		}
+	}
+
+	for id := range s.state {
+		if !seen[id] {
+			delete(s.state, id)
+		}
+	}
+
+	if err := s.save(); err != nil {
+		logger.Error("Failed save reminders state", "fp", s.stateFp, "error", err)
+	}
+
+	return counter
+}
+
+func (s *Scheduler) notify(notifiers []Notifier, r Reminder) {
+	for _, n := range notifiers {
+		if err := n.Notify(r); err != nil {
+			logger.Error("Failed notify", "notifier", n, "id", r.Task.ID, "error", err)
+		}
+	}
+}
+
+func (s *Scheduler) load() error {
+	data, err := os.ReadFile(s.stateFp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &s.state)
+}
+
+func (s *Scheduler) save() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.stateFp, data)
+}
+
+func writeFileAtomic(fp string, data []byte) error {
+	tmp := fp + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fp)
+}
@@ -0,0 +1,125 @@
+package reminders
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+type recordNotifier struct {
+	fired []Reminder
+}
+
+func (n *recordNotifier) Notify(r Reminder) error {
+	n.fired = append(n.fired, r)
+	return nil
+}
+
+func TestScheduler_Check(t *testing.T) {
+	stateFp := filepath.Join(t.TempDir(), "reminders.json")
+	now := time.Date(2025, time.April, 16, 12, 0, 0, 0, time.UTC)
+
+	soon := db.NewTaskBuilder(db.UuidIdGenerator).WithName("soon").WithTime(now.Add(30 * time.Minute)).Build()
+	late := db.NewTaskBuilder(db.UuidIdGenerator).WithName("late").WithTime(now.Add(-time.Minute)).Build()
+	later := db.NewTaskBuilder(db.UuidIdGenerator).WithName("later").WithTime(now.Add(5 * time.Hour)).Build()
+	noDue := db.NewTaskBuilder(db.UuidIdGenerator).WithName("no due").Build()
+	tasks := []*db.Task{soon, late, later, noDue}
+
+	thresholds := []time.Duration{0, time.Hour, 10 * time.Minute}
+	n := &recordNotifier{}
+	s := NewScheduler(thresholds, []Notifier{n}, stateFp)
+
+	if c := s.Check(tasks, now); c != 2 {
+		t.Fatalf("expected 2 reminders, got %d", c)
+	}
+	if n.fired[0].Task != soon || n.fired[0].Threshold != time.Hour {
+		t.Errorf("unexpected reminder for approaching task: %+v", n.fired[0])
+	}
+	if n.fired[1].Task != late || !n.fired[1].Overdue {
+		t.Errorf("overdue task should fire only the overdue reminder: %+v", n.fired[1])
+	}
+
+	if c := s.Check(tasks, now.Add(time.Minute)); c != 0 {
+		t.Errorf("reminders must fire once per threshold, fired %d again", c)
+	}
+
+	restarted := NewScheduler(thresholds, []Notifier{n}, stateFp)
+	if c := restarted.Check(tasks, now.Add(time.Minute)); c != 0 {
+		t.Errorf("reminders state must survive restart, fired %d again", c)
+	}
+	if c := restarted.Check(tasks, now.Add(25*time.Minute)); c != 1 {
+		t.Errorf("expected next threshold to fire, got %d", c)
+	}
+
+	soon.Time = now.Add(3 * time.Hour)
+	if c := restarted.Check(tasks, now.Add(2*time.Hour+30*time.Minute)); c != 1 {
+		t.Errorf("changed due time should reset fired reminders, got %d", c)
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var received Reminder
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	task := db.NewTaskBuilder(db.UuidIdGenerator).WithName("call").Build()
+	if err := NewWebhookNotifier(server.URL).Notify(Reminder{Task: task, Overdue: true}); err != nil {
+		t.Fatalf("Notify returned an error: %v", err)
+	}
+	if received.Task == nil || received.Task.ID != task.ID || !received.Overdue {
+		t.Errorf("webhook received unexpected reminder: %+v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if err := NewWebhookNotifier(failing.URL).Notify(Reminder{Task: task}); err == nil {
+		t.Errorf("expected error on non 2xx response")
+	}
+}
+
+func TestOutboxNotifier_Notify(t *testing.T) {
+	dir := t.TempDir()
+	task := db.NewTaskBuilder(db.UuidIdGenerator).WithName("pay").Build()
+
+	if err := (OutboxNotifier{Dir: dir}).Notify(Reminder{Task: task, Threshold: time.Hour}); err != nil {
+		t.Fatalf("Notify returned an error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one reminder file, got %v, %v", entries, err)
+	}
+	if expected := "remind_" + task.ID.String() + "_3600.json"; entries[0].Name() != expected {
+		t.Errorf("expected %v, got %v", expected, entries[0].Name())
+	}
+}
+
+func TestScheduler_WithOverdue(t *testing.T) {
+	stateFp := filepath.Join(t.TempDir(), "reminders.json")
+	now := time.Date(2025, time.April, 16, 12, 0, 0, 0, time.UTC)
+	task := db.NewTaskBuilder(db.UuidIdGenerator).WithName("late").WithTime(now.Add(30 * time.Minute)).Build()
+
+	n, overdue := &recordNotifier{}, &recordNotifier{}
+	s := NewScheduler([]time.Duration{time.Hour}, []Notifier{n}, stateFp).WithOverdue(overdue)
+
+	s.Check([]*db.Task{task}, now)
+	if len(n.fired) != 1 || len(overdue.fired) != 0 {
+		t.Fatalf("expected only the 1h reminder, got %+v and %+v", n.fired, overdue.fired)
+	}
+
+	restarted := NewScheduler([]time.Duration{time.Hour}, []Notifier{n}, stateFp).WithOverdue(overdue)
+	restarted.Check([]*db.Task{task}, now.Add(time.Hour))
+	restarted.Check([]*db.Task{task}, now.Add(2*time.Hour))
+	if len(overdue.fired) != 1 || !overdue.fired[0].Overdue || len(n.fired) != 1 {
+		t.Errorf("overdue notifiers must fire once without a 0s threshold, got %+v", overdue.fired)
+	}
+}
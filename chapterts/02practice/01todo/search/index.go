@@ -0,0 +1,184 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	nameWeight   = 3.0
+	descWeight   = 1.0
+	prefixWeight = 0.5
+)
+
+type Document struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+type Result struct {
+	ID    string
+	Score float64
+}
+
+type posting struct {
+	name, desc int
+}
+
+// Index is an inverted index over task names and descriptions, updated
+// incrementally with Put and Remove. Terms are kept sorted for prefix
+// lookups. It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string][]string
+	postings map[string]map[string]*posting
+	terms    []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[string][]string{},
+		postings: map[string]map[string]*posting{},
+	}
+}
+
+// Put adds the document or replaces a previously indexed one with the same id.
+func (ix *Index) Put(d Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(d.ID)
+
+	terms := map[string]bool{}
+	add := func(text string, field func(p *posting)) {
+		for _, term := range Tokenize(text) {
+			docs, ok := ix.postings[term]
+			if !ok {
+				docs = map[string]*posting{}
+				ix.postings[term] = docs
+				ix.insertTerm(term)
+			}
+			p, ok := docs[d.ID]
+			if !ok {
+				p = &posting{}
+				docs[d.ID] = p
+			}
+			field(p)
+			terms[term] = true
+		}
+	}
+	add(d.Name, func(p *posting) { p.name++ })
+	add(d.Description, func(p *posting) { p.desc++ })
+
+	docTerms := make([]string, 0, len(terms))
+	for term := range terms {
+		docTerms = append(docTerms, term)
+	}
+	ix.docs[d.ID] = docTerms
+}
+
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	for _, term := range ix.docs[id] {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+			ix.deleteTerm(term)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.docs)
+}
+
+// Search returns documents matching every query word, exactly or by prefix,
+// ranked by tf-idf with name matches weighted above description matches.
+func (ix *Index) Search(query string) []Result {
+	queryTerms := Tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	total := float64(len(ix.docs))
+	var scores map[string]float64
+	for _, qt := range queryTerms {
+		best := map[string]float64{}
+		for _, term := range ix.withPrefix(qt) {
+			docs := ix.postings[term]
+			idf := math.Log(1 + total/float64(len(docs)))
+			weight := 1.0
+			if term != qt {
+				weight = prefixWeight
+			}
+			for id, p := range docs {
+				score := weight * idf * (nameWeight*float64(p.name) + descWeight*float64(p.desc))
+				best[id] = math.Max(best[id], score)
+			}
+		}
+
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if s, ok := best[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{id, score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	return results
+}
+
+func (ix *Index) insertTerm(term string) {
+	i := sort.SearchStrings(ix.terms, term)
+	ix.terms = append(ix.terms, "")
+	copy(ix.terms[i+1:], ix.terms[i:])
+	ix.terms[i] = term
+}
+
+func (ix *Index) deleteTerm(term string) {
+	i := sort.SearchStrings(ix.terms, term)
+	if i < len(ix.terms) && ix.terms[i] == term {
+		ix.terms = append(ix.terms[:i], ix.terms[i+1:]...)
+	}
+}
+
+func (ix *Index) withPrefix(prefix string) []string {
+	i := sort.SearchStrings(ix.terms, prefix)
+	matched := []string{}
+	for ; i < len(ix.terms) && strings.HasPrefix(ix.terms[i], prefix); i++ {
+		matched = append(matched, ix.terms[i])
+	}
+	return matched
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func resultIDs(results []Result) []string {
+	ids := []string{}
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestFold(t *testing.T) {
+	var tests = []struct {
+		input, expected string
+	}{
+		{"Café", "cafe"},
+		{"ÉLAN Über", "elan uber"},
+		{"naïve façade", "naive facade"},
+		{"plain", "plain"},
+	}
+
+	for _, tt := range tests {
+		if got := Fold(tt.input); got != tt.expected {
+			t.Errorf("Fold(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(Document{ID: "1", Name: "Send invoice", Description: "Client ACME, café order"})
+	ix.Put(Document{ID: "2", Name: "Call client", Description: "ask about the invoice"})
+	ix.Put(Document{ID: "3", Name: "Invoicing tool research"})
+	ix.Put(Document{ID: "4", Name: "Pay rent"})
+
+	var tests = []struct {
+		query    string
+		expected []string
+	}{
+		{"invoice client", []string{"1", "2"}},
+		{"INVOICE", []string{"1", "2"}},
+		{"inv", []string{"3", "1", "2"}},
+		{"cafe", []string{"1"}},
+		{"client rent", []string{}},
+		{"   ", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := resultIDs(ix.Search(tt.query)); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIndex_Incremental(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(Document{ID: "1", Name: "Buy milk"})
+	ix.Put(Document{ID: "2", Name: "Buy bread"})
+
+	ix.Put(Document{ID: "1", Name: "Buy oat drink"})
+	if got := resultIDs(ix.Search("milk")); len(got) != 0 {
+		t.Errorf("replaced document still matches old text: %v", got)
+	}
+	if got := resultIDs(ix.Search("oat")); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("replaced document does not match new text: %v", got)
+	}
+
+	ix.Remove("2")
+	if got := resultIDs(ix.Search("buy")); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("removed document still found: %v", got)
+	}
+	if len(ix.terms) != 3 || ix.Len() != 1 {
+		t.Errorf("unused terms must be dropped, got %v", ix.terms)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("Send the Invoice to Café Élan", "invoice cafe el", "[", "]")
+	if expected := "Send the [Invoice] to [Café] [Élan]"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
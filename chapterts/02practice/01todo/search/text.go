@@ -0,0 +1,89 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold lowercases s and strips diacritics, so "Café" and "cafe" match.
+func Fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// words splits s into words keeping their byte offsets in s.
+func words(s string) []token {
+	tokens := []token{}
+	start := -1
+	for i, r := range s {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{s[start:i], start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{s[start:], start, len(s)})
+	}
+	return tokens
+}
+
+// Tokenize returns folded words of s.
+func Tokenize(s string) []string {
+	ws := words(s)
+	terms := make([]string, 0, len(ws))
+	for _, w := range ws {
+		terms = append(terms, Fold(w.text))
+	}
+	return terms
+}
+
+// Highlight wraps every word of text matching a query term (by prefix)
+// with open and close markers.
+func Highlight(text, query, open, close string) string {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, w := range words(text) {
+		folded := Fold(w.text)
+		for _, term := range terms {
+			if strings.HasPrefix(folded, term) {
+				b.WriteString(text[last:w.start])
+				b.WriteString(open)
+				b.WriteString(w.text)
+				b.WriteString(close)
+				last = w.end
+				break
+			}
+		}
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
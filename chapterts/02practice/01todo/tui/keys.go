@@ -0,0 +1,88 @@
+package tui
+
+import "unicode/utf8"
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPgUp
+	keyPgDown
+	keyEnter
+	keyBackspace
+	keyTab
+	keyEsc
+	keyCtrlC
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+var escapes = map[string]keyCode{
+	"\x1b[A": keyUp, "\x1bOA": keyUp,
+	"\x1b[B": keyDown, "\x1bOB": keyDown,
+	"\x1b[C": keyRight, "\x1bOC": keyRight,
+	"\x1b[D": keyLeft, "\x1bOD": keyLeft,
+	"\x1b[H": keyHome, "\x1b[1~": keyHome,
+	"\x1b[F": keyEnd, "\x1b[4~": keyEnd,
+	"\x1b[5~": keyPgUp,
+	"\x1b[6~": keyPgDown,
+}
+
+// parseKeys splits raw terminal input into key presses. Unknown escape
+// sequences are dropped.
+func parseKeys(b []byte) []key {
+	keys := []key{}
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			n := escapeLen(b)
+			if n == 1 {
+				keys = append(keys, key{code: keyEsc})
+			} else if code, ok := escapes[string(b[:n])]; ok {
+				keys = append(keys, key{code: code})
+			}
+			b = b[n:]
+			continue
+		}
+
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, key{code: keyEnter})
+		case 0x7f, 0x08:
+			keys = append(keys, key{code: keyBackspace})
+		case '\t':
+			keys = append(keys, key{code: keyTab})
+		case 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= ' ' {
+				keys = append(keys, key{code: keyRune, r: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+func escapeLen(b []byte) int {
+	if len(b) < 2 || (b[1] != '[' && b[1] != 'O') {
+		return 1
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,523 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"todo/cli/db"
+)
+
+const (
+	detailHeight = 7
+	timeLayout   = "2006-01-02 15:04"
+	helpLine     = "j/k move  space done  a add  e edit  d delete  / filter  h hide done  r reload  q quit"
+)
+
+type Options struct {
+	Load     func() *db.Storage
+	Add      func(s *db.Storage, t *db.Task) error
+	MarkDone func(s *db.Storage, id string) error
+	Delete   func(s *db.Storage, id string) error
+	ParseDue func(string) (time.Time, error)
+	ModTime  func() time.Time
+	Location *time.Location
+}
+
+type inputLine struct {
+	prompt   string
+	value    []rune
+	confirm  bool
+	onChange func(string)
+	onSubmit func(string) error
+}
+
+type model struct {
+	opts     Options
+	store    *db.Storage
+	lastMod  time.Time
+	tasks    []*db.Task
+	short    map[string]string
+	cursor   int
+	offset   int
+	filter   string
+	hideDone bool
+	width    int
+	height   int
+	input    *inputLine
+	status   string
+	quit     bool
+}
+
+func newModel(opts Options) *model {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	m := &model{opts: opts, width: 80, height: 24}
+	m.reload()
+	return m
+}
+
+func (m *model) reload() {
+	m.store = m.opts.Load()
+	m.lastMod = m.opts.ModTime()
+	m.refresh()
+}
+
+// refresh rebuilds the visible task list, keeping the cursor on the same
+// task when it is still visible.
+func (m *model) refresh() {
+	selected := m.selected()
+
+	all := m.store.ListTasks()
+	m.short = m.store.ShortIDs()
+	needle := strings.ToLower(m.filter)
+
+	m.tasks = m.tasks[:0]
+	for _, t := range all {
+		if m.hideDone && t.Done {
+			continue
+		}
+		if needle != "" && !m.matches(t, needle) {
+			continue
+		}
+		m.tasks = append(m.tasks, t)
+	}
+	slices.SortFunc(m.tasks, db.CompareSeq)
+
+	if selected != nil {
+		for i, t := range m.tasks {
+			if t.ID == selected.ID {
+				m.cursor = i
+			}
+		}
+	}
+	m.cursor = max(0, min(m.cursor, len(m.tasks)-1))
+}
+
+func (m *model) matches(t *db.Task, needle string) bool {
+	return strings.Contains(strings.ToLower(t.Name), needle) ||
+		strings.Contains(strings.ToLower(t.Description), needle) ||
+		strings.HasPrefix(t.ID.String(), needle) ||
+		fmt.Sprint(t.Seq) == needle ||
+		strings.ToLower(t.Ref()) == needle
+}
+
+func (m *model) selected() *db.Task {
+	if m.cursor < 0 || m.cursor >= len(m.tasks) {
+		return nil
+	}
+	return m.tasks[m.cursor]
+}
+
+// checkDisk reloads the storage when another process (e.g. the daemon)
+// saved it since the last load.
+func (m *model) checkDisk() bool {
+	if m.input != nil {
+		return false
+	}
+	if mod := m.opts.ModTime(); mod.After(m.lastMod) {
+		m.reload()
+		m.status = "reloaded: storage changed on disk"
+		return true
+	}
+	return false
+}
+
+func (m *model) save() {
+	if err := m.store.Save(); err != nil {
+		m.status = "save failed: " + err.Error()
+		return
+	}
+	m.lastMod = m.opts.ModTime()
+}
+
+func (m *model) handle(k key) {
+	if m.input != nil {
+		m.handleInput(k)
+		return
+	}
+
+	m.status = ""
+	switch {
+	case k.code == keyCtrlC, k.code == keyRune && k.r == 'q':
+		m.quit = true
+	case k.code == keyDown, k.code == keyRune && k.r == 'j':
+		m.move(1)
+	case k.code == keyUp, k.code == keyRune && k.r == 'k':
+		m.move(-1)
+	case k.code == keyPgDown:
+		m.move(m.listHeight())
+	case k.code == keyPgUp:
+		m.move(-m.listHeight())
+	case k.code == keyHome, k.code == keyRune && k.r == 'g':
+		m.move(-len(m.tasks))
+	case k.code == keyEnd, k.code == keyRune && k.r == 'G':
+		m.move(len(m.tasks))
+	case k.code == keyRune && (k.r == ' ' || k.r == 'x'):
+		m.toggle()
+	case k.code == keyRune && k.r == 'a':
+		m.startAdd()
+	case k.code == keyEnter, k.code == keyRune && k.r == 'e':
+		m.startEdit()
+	case k.code == keyRune && k.r == 'd':
+		m.startDelete()
+	case k.code == keyRune && k.r == '/':
+		m.startFilter()
+	case k.code == keyRune && k.r == 'h':
+		m.hideDone = !m.hideDone
+		m.refresh()
+	case k.code == keyRune && k.r == 'r':
+		m.reload()
+		m.status = "reloaded"
+	case k.code == keyEsc:
+		m.filter = ""
+		m.refresh()
+	}
+}
+
+func (m *model) handleInput(k key) {
+	in := m.input
+	if in.confirm {
+		m.input = nil
+		if k.code == keyRune && (k.r == 'y' || k.r == 'Y') {
+			m.submit(in, "")
+		} else {
+			m.status = "cancelled"
+		}
+		return
+	}
+
+	switch k.code {
+	case keyEsc, keyCtrlC:
+		m.input = nil
+		if in.onChange != nil {
+			in.onChange("")
+		}
+		return
+	case keyEnter:
+		m.input = nil
+		m.submit(in, string(in.value))
+		return
+	case keyBackspace:
+		if len(in.value) > 0 {
+			in.value = in.value[:len(in.value)-1]
+		}
+	case keyRune:
+		in.value = append(in.value, k.r)
+	default:
+		return
+	}
+
+	if in.onChange != nil {
+		in.onChange(string(in.value))
+	}
+}
+
+func (m *model) submit(in *inputLine, value string) {
+	if in.onSubmit == nil {
+		return
+	}
+	if err := in.onSubmit(value); err != nil {
+		m.status = "error: " + err.Error()
+		return
+	}
+	m.refresh()
+}
+
+func (m *model) move(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.tasks)-1))
+}
+
+func (m *model) toggle() {
+	t := m.selected()
+	if t == nil {
+		return
+	}
+
+	if !t.Done {
+		if err := m.opts.MarkDone(m.store, t.ID.String()); err != nil {
+			m.status = "error: " + err.Error()
+			return
+		}
+	} else {
+		reopened := *t
+		reopened.Done = false
+		reopened.DoneAt = nil
+		if err := m.store.UpdateTask(&reopened); err != nil {
+			m.status = "error: " + err.Error()
+			return
+		}
+	}
+
+	m.save()
+	m.refresh()
+}
+
+func (m *model) startAdd() {
+	m.input = &inputLine{
+		prompt: "add (name | description | due): ",
+		onSubmit: func(v string) error {
+			name, desc, due, err := m.parseFields(v)
+			if err != nil {
+				return err
+			}
+
+			t := db.NewTaskBuilder(db.UuidIdGenerator).
+				WithName(name).
+				WithDescription(desc).
+				WithTime(due).
+				Build()
+			if err := m.opts.Add(m.store, t); err != nil {
+				return err
+			}
+
+			m.save()
+			m.filter = ""
+			m.refresh()
+			m.status = "added"
+			for i, added := range m.tasks {
+				if added.ID == t.ID {
+					m.cursor = i
+					m.status = "added " + added.Ref()
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (m *model) startEdit() {
+	t := m.selected()
+	if t == nil {
+		return
+	}
+
+	current := t.Name + " | " + t.Description
+	if !t.Time.IsZero() {
+		current += " | " + t.Time.In(m.opts.Location).Format(timeLayout)
+	}
+
+	m.input = &inputLine{
+		prompt: fmt.Sprintf("edit %s (name | description | due): ", t.Ref()),
+		value:  []rune(current),
+		onSubmit: func(v string) error {
+			name, desc, due, err := m.parseFields(v)
+			if err != nil {
+				return err
+			}
+
+			edited := *t
+			edited.Name = name
+			edited.Description = desc
+			edited.Time = due
+			if err := m.store.UpdateTask(&edited); err != nil {
+				return err
+			}
+
+			m.save()
+			m.status = fmt.Sprintf("updated %s", t.Ref())
+			return nil
+		},
+	}
+}
+
+func (m *model) startDelete() {
+	t := m.selected()
+	if t == nil {
+		return
+	}
+
+	m.input = &inputLine{
+		prompt:  fmt.Sprintf("delete %s %q? (y/n)", t.Ref(), t.Name),
+		confirm: true,
+		onSubmit: func(string) error {
+			if err := m.opts.Delete(m.store, t.ID.String()); err != nil {
+				return err
+			}
+			m.save()
+			m.status = fmt.Sprintf("deleted %s", t.Ref())
+			return nil
+		},
+	}
+}
+
+func (m *model) startFilter() {
+	m.input = &inputLine{
+		prompt: "filter: ",
+		value:  []rune(m.filter),
+		onChange: func(v string) {
+			m.filter = v
+			m.refresh()
+		},
+		onSubmit: func(v string) error {
+			m.filter = v
+			return nil
+		},
+	}
+}
+
+func (m *model) parseFields(v string) (string, string, time.Time, error) {
+	parts := strings.SplitN(v, "|", 3)
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+
+	if parts[0] == "" {
+		return "", "", time.Time{}, fmt.Errorf("name is required")
+	}
+
+	var due time.Time
+	if parts[2] != "" {
+		var err error
+		if due, err = m.opts.ParseDue(parts[2]); err != nil {
+			return "", "", time.Time{}, err
+		}
+	}
+
+	return parts[0], parts[1], due, nil
+}
+
+func (m *model) listHeight() int {
+	h := m.height - 2
+	if m.showDetail() {
+		h -= detailHeight
+	}
+	return max(1, h)
+}
+
+func (m *model) showDetail() bool {
+	return m.height >= detailHeight+6
+}
+
+// view renders the whole screen as exactly m.height lines.
+func (m *model) view() string {
+	lines := make([]string, 0, m.height)
+
+	header := fmt.Sprintf(" todo: %d tasks", len(m.tasks))
+	if m.filter != "" {
+		header += fmt.Sprintf("  filter: %q", m.filter)
+	}
+	if m.hideDone {
+		header += "  (done hidden)"
+	}
+	lines = append(lines, "\x1b[1m"+pad(header, m.width)+"\x1b[0m")
+
+	listHeight := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+listHeight {
+		m.offset = m.cursor - listHeight + 1
+	}
+
+	now := time.Now()
+	for i := m.offset; i < m.offset+listHeight; i++ {
+		if i >= len(m.tasks) {
+			lines = append(lines, "")
+			continue
+		}
+
+		row := pad(m.row(m.tasks[i], now), m.width)
+		switch {
+		case i == m.cursor:
+			row = "\x1b[7m" + row + "\x1b[0m"
+		case m.tasks[i].Done:
+			row = "\x1b[2m" + row + "\x1b[0m"
+		}
+		lines = append(lines, row)
+	}
+
+	if m.showDetail() {
+		lines = append(lines, strings.Repeat("─", m.width))
+		lines = append(lines, m.detail()...)
+	}
+
+	switch {
+	case m.input != nil:
+		lines = append(lines, pad(m.input.prompt+string(m.input.value), m.width))
+	case m.status != "":
+		lines = append(lines, pad(m.status, m.width))
+	default:
+		lines = append(lines, "\x1b[2m"+pad(helpLine, m.width)+"\x1b[0m")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m *model) row(t *db.Task, now time.Time) string {
+	check := "[ ]"
+	if t.Done {
+		check = "[x]"
+	}
+
+	due := ""
+	if !t.Time.IsZero() {
+		due = "  due " + t.Time.In(m.opts.Location).Format("Mon 02 Jan 15:04")
+		if !t.Done && t.Time.Before(now) {
+			due += " !"
+		}
+	}
+
+	return fmt.Sprintf(" %s %-5s %-13s %s%s", check, t.Ref(), m.short[t.ID.String()], t.Name, due)
+}
+
+func (m *model) detail() []string {
+	lines := make([]string, 0, detailHeight-1)
+	t := m.selected()
+	if t == nil {
+		lines = append(lines, " no task selected")
+	} else {
+		due, doneAt := "-", "-"
+		if !t.Time.IsZero() {
+			due = t.Time.In(m.opts.Location).Format(timeLayout)
+		}
+		if t.DoneAt != nil {
+			doneAt = t.DoneAt.In(m.opts.Location).Format(timeLayout)
+		}
+
+		lines = append(lines,
+			pad(fmt.Sprintf(" %s  %s", t.Ref(), t.ID), m.width),
+			pad(" "+t.Name, m.width),
+			pad(fmt.Sprintf(" due: %s   done: %s", due, doneAt), m.width),
+		)
+		for _, l := range wrap(t.Description, m.width-2) {
+			lines = append(lines, " "+l)
+		}
+	}
+
+	for len(lines) < detailHeight-1 {
+		lines = append(lines, "")
+	}
+	return lines[:detailHeight-1]
+}
+
+func pad(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+func wrap(s string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
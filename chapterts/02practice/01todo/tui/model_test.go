@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"todo/cli/db"
+)
+
+func typeKeys(m *model, s string) {
+	for _, k := range parseKeys([]byte(s)) {
+		m.handle(k)
+	}
+}
+
+func testModel(t *testing.T) (*model, *time.Time) {
+	t.Chdir(t.TempDir())
+
+	modTime := time.Now()
+	m := newModel(Options{
+		Load:     db.GetStorage,
+		Add:      func(s *db.Storage, t *db.Task) error { return s.AddTask(t) },
+		MarkDone: func(s *db.Storage, id string) error { _, err := s.MarkDone(id); return err },
+		Delete:   func(s *db.Storage, id string) error { return s.DeleteTask(id) },
+		ParseDue: func(s string) (time.Time, error) { return time.Parse(timeLayout, s) },
+		ModTime:  func() time.Time { return modTime },
+		Location: time.UTC,
+	})
+	m.width, m.height = 80, 20
+	return m, &modTime
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("a\x1b[A\x1b[B\r\x7f\x1bé\x1b[99~"))
+	expected := []key{
+		{code: keyRune, r: 'a'},
+		{code: keyUp},
+		{code: keyDown},
+		{code: keyEnter},
+		{code: keyBackspace},
+		{code: keyEsc},
+		{code: keyRune, r: 'é'},
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("key %d: expected %v, got %v", i, expected[i], keys[i])
+		}
+	}
+}
+
+func TestModel_AddEditToggleDelete(t *testing.T) {
+	m, _ := testModel(t)
+
+	typeKeys(m, "aBuy milk | 2 bottles | 2025-04-18 10:00\r")
+	typeKeys(m, "aWalk dog\r")
+	if len(m.tasks) != 2 || m.selected().Name != "Walk dog" {
+		t.Fatalf("expected 2 tasks with new one selected, got %v", m.tasks)
+	}
+	if m.status != "added #2" {
+		t.Errorf("expected status 'added #2', got %q", m.status)
+	}
+	if m.tasks[0].Time != time.Date(2025, time.April, 18, 10, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected due time %v", m.tasks[0].Time)
+	}
+
+	typeKeys(m, "k")
+	typeKeys(m, "e\x7f\x7f\x7f\x7f\x7f11:30\r")
+	if got := m.selected(); got.Name != "Buy milk" || got.Time.Hour() != 11 || got.Seq != 1 {
+		t.Errorf("edit not applied: %+v", got)
+	}
+
+	typeKeys(m, " ")
+	if !m.selected().Done {
+		t.Errorf("task should be marked done")
+	}
+	typeKeys(m, "h")
+	if len(m.tasks) != 1 {
+		t.Errorf("done task should be hidden, got %v", m.tasks)
+	}
+	typeKeys(m, "h")
+	typeKeys(m, "g ")
+	if m.selected().Done || m.selected().DoneAt != nil {
+		t.Errorf("task should be reopened")
+	}
+
+	typeKeys(m, "dn")
+	if len(m.tasks) != 2 {
+		t.Errorf("delete should be cancelled")
+	}
+	typeKeys(m, "dy")
+	if len(m.tasks) != 1 || m.selected().Name != "Walk dog" {
+		t.Errorf("expected only 'Walk dog' left, got %v", m.tasks)
+	}
+
+	saved := db.GetStorage().ListTasks()
+	if len(saved) != 1 {
+		t.Errorf("changes were not saved, storage has %v", saved)
+	}
+}
+
+func TestModel_AddShowsStoredNumber(t *testing.T) {
+	m, _ := testModel(t)
+	m.opts.Add = func(s *db.Storage, t *db.Task) error {
+		// Like a pre-add hook, store a changed copy of the task.
+		hooked := *t
+		hooked.List = "work"
+		return s.AddTask(&hooked)
+	}
+
+	typeKeys(m, "aCall plumber\r")
+	if m.status != "added work#1" {
+		t.Errorf("expected status 'added work#1', got %q", m.status)
+	}
+}
+
+func TestModel_FilterAndErrors(t *testing.T) {
+	m, _ := testModel(t)
+	typeKeys(m, "aInvoice client\raPay rent\raSend invoice\r")
+
+	typeKeys(m, "/INVO")
+	if len(m.tasks) != 2 {
+		t.Errorf("expected 2 tasks matching filter, got %v", m.tasks)
+	}
+	typeKeys(m, "\r\x1b")
+	if len(m.tasks) != 3 {
+		t.Errorf("esc should clear filter, got %v", m.tasks)
+	}
+
+	typeKeys(m, "a | no name\r")
+	if !strings.HasPrefix(m.status, "error:") {
+		t.Errorf("expected error status, got %q", m.status)
+	}
+	typeKeys(m, "aX | y | not a date\r")
+	if !strings.HasPrefix(m.status, "error:") || len(m.tasks) != 3 {
+		t.Errorf("invalid due must be rejected, status %q", m.status)
+	}
+}
+
+func TestModel_ReloadsOnDiskChange(t *testing.T) {
+	m, modTime := testModel(t)
+
+	other := db.GetStorage()
+	other.AddTask(db.NewTaskBuilder(db.UuidIdGenerator).WithName("from daemon").Build())
+	other.Save()
+
+	if m.checkDisk() {
+		t.Errorf("unchanged mod time should not reload")
+	}
+	*modTime = modTime.Add(time.Second)
+	if !m.checkDisk() || len(m.tasks) != 1 {
+		t.Errorf("expected reload with daemon task, got %v", m.tasks)
+	}
+}
+
+func TestModel_View(t *testing.T) {
+	m, _ := testModel(t)
+	typeKeys(m, "aBuy milk | 2 bottles\r")
+
+	lines := strings.Split(m.view(), "\n")
+	if len(lines) != m.height {
+		t.Errorf("expected %d lines, got %d", m.height, len(lines))
+	}
+	if !strings.Contains(lines[1], "[ ] #1") || !strings.Contains(lines[1], "Buy milk") {
+		t.Errorf("unexpected task row %q", lines[1])
+	}
+	if !strings.Contains(m.view(), "2 bottles") {
+		t.Errorf("detail pane should show description")
+	}
+}
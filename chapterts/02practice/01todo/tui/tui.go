@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const diskCheckPeriod = time.Second
+
+// Run shows the full-screen task list until the user quits. The screen is
+// drawn with plain ANSI escape sequences on stdout.
+func Run(opts Options) error {
+	fd := int(os.Stdin.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("terminal raw mode: %w", err)
+	}
+	defer restore()
+
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	m := newModel(opts)
+	resize := func() {
+		if w, h, err := terminalSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 0 {
+			m.width, m.height = w, h
+		}
+	}
+	resize()
+
+	keys := make(chan []key)
+	go readKeys(keys)
+
+	winch := make(chan os.Signal, 1)
+	if len(resizeSignals) > 0 {
+		signal.Notify(winch, resizeSignals...)
+		defer signal.Stop(winch)
+	}
+
+	ticker := time.NewTicker(diskCheckPeriod)
+	defer ticker.Stop()
+
+	redraw := true
+	for !m.quit {
+		if redraw {
+			fmt.Print("\x1b[H\x1b[2J" + m.view())
+		}
+
+		select {
+		case batch, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range batch {
+				m.handle(k)
+			}
+			redraw = true
+		case <-winch:
+			resize()
+			redraw = true
+		case <-ticker.C:
+			redraw = m.checkDisk()
+		}
+	}
+
+	return nil
+}
+
+func readKeys(keys chan<- []key) {
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- parseKeys(buf[:n])
+	}
+}
@@ -1,11 +1,38 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"notes/api/server"
+	"os"
 	"sync"
 )
 
 func main() {
+	dataDir := flag.String("data", "data", "dir the notes are stored in, empty keeps them in memory only")
+	issueToken := flag.String("issue-token", "", "issue a token for the author with this id, print its secret and exit")
+	adoptNotes := flag.String("adopt-notes", "", "give notes without an author to the author with this id and exit")
+	flag.Parse()
+
+	switch {
+	case *issueToken != "":
+		secret, err := server.IssueToken(*dataDir, *issueToken)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "issue token:", err)
+			os.Exit(1)
+		}
+		fmt.Println(secret)
+		return
+	case *adoptNotes != "":
+		n, err := server.AdoptNotes(*dataDir, *adoptNotes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "adopt notes:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d notes adopted\n", n)
+		return
+	}
+
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
@@ -13,7 +40,7 @@ func main() {
 	go func() {
 		defer wg.Done()
 
-		s := server.NewServer([4]byte{127, 0, 0, 1}, 8090)
+		s := server.NewServer([4]byte{127, 0, 0, 1}, 8090, *dataDir)
 		s.Run()
 	}()
 }
@@ -1,14 +1,11 @@
 package models
 
-import (
-	"encoding/json"
-	"fmt"
-	"io"
-)
+import "io"
 
 const authorModelName ModelName = "author"
 
 type Author struct {
+	Versioned
 	ID         *ObjectID `json:"id"`
 	Username   string    `json:"username"`
 	Firstname  *string   `json:"firstname"`
@@ -16,14 +13,7 @@ type Author struct {
 }
 
 func authorFromBytes(r io.Reader) (ObjectsModel, error) {
-	var author Author
-	if err := json.NewDecoder(r).Decode(&author); err != nil {
-		return nil, err
-	}
-	if author.Username == "" {
-		return nil, fmt.Errorf("invalid username")
-	}
-	return &author, nil
+	return parseObject(r, authorModelName, &Author{})
 }
 
 func (a *Author) getID() ObjectID {
@@ -35,3 +25,14 @@ func (a *Author) SetID(id *ObjectID) {
 }
 
 func (a *Author) setDefaults() {}
+
+func (a *Author) fields() map[string]any {
+	return map[string]any{
+		"id":         a.ID,
+		"username":   a.Username,
+		"firstname":  a.Firstname,
+		"secondname": a.Secondname,
+	}
+}
+
+func (a *Author) keepManaged(stored ObjectsModel) {}
@@ -27,3 +27,38 @@ func NewAlreadyExistsError(name ModelName, id ObjectID) *AlreadyExistsError {
 func (e *AlreadyExistsError) Error() string {
 	return fmt.Sprintf("%s(id=%v) already exists", e.ModelName, e.ID)
 }
+
+// ForeignKeyError reports a Field of a ModelName object referencing a
+// Target object that doesn't exist.
+type ForeignKeyError struct {
+	ModelName ModelName
+	Field     string
+	Target    ModelName
+	ID        ObjectID
+}
+
+func NewForeignKeyError(name ModelName, field string, target ModelName, id ObjectID) *ForeignKeyError {
+	return &ForeignKeyError{name, field, target, id}
+}
+
+func (e *ForeignKeyError) Error() string {
+	return fmt.Sprintf("%s.%s: %s(id=%v) not exists", e.ModelName, e.Field, e.Target, e.ID)
+}
+
+// ReferencedError reports an object which can't be deleted while Count
+// objects of By reference it in Field.
+type ReferencedError struct {
+	ModelName ModelName
+	ID        ObjectID
+	By        ModelName
+	Field     string
+	Count     int
+}
+
+func NewReferencedError(name ModelName, id ObjectID, by ModelName, field string, count int) *ReferencedError {
+	return &ReferencedError{name, id, by, field, count}
+}
+
+func (e *ReferencedError) Error() string {
+	return fmt.Sprintf("%s(id=%v) is referenced by %d %s.%s", e.ModelName, e.ID, e.Count, e.By, e.Field)
+}
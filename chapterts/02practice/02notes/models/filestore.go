@@ -0,0 +1,345 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+const (
+	walExt               = ".wal"
+	snapshotExt          = ".snapshot.json"
+	defaultSnapshotEvery = 1000
+)
+
+const (
+	walPut    = "put"
+	walDelete = "delete"
+)
+
+type walRecord struct {
+	Op     string          `json:"op"`
+	ID     ObjectID        `json:"id"`
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// walFile is the log file, tests replace it to inject failed writes.
+type walFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// fileTable keeps objects in memory like Table and makes every change
+// durable by appending it to a write-ahead log before applying it. Every
+// snapshotEvery records the log is compacted into a snapshot file. Reads
+// go straight to memory, writes are serialized by mu so the log keeps the
+// order they were applied in.
+type fileTable struct {
+	mu         sync.Mutex
+	mem        *Table
+	newModel   func() ObjectsModel
+	walFp      string
+	snapshotFp string
+	wal        walFile
+	// walSize is the length of the log up to its last complete record.
+	walSize int64
+	// broken is set when a failed append couldn't be undone, the table
+	// refuses writes after it.
+	broken        error
+	records       int
+	snapshotEvery int
+}
+
+func openFileTable(dir string, name ModelName, snapshotEvery int) (*fileTable, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	t := &fileTable{
+		mem:           newTable(name),
+		newModel:      modelFactories[name],
+		walFp:         filepath.Join(dir, string(name)+walExt),
+		snapshotFp:    filepath.Join(dir, string(name)+snapshotExt),
+		snapshotEvery: snapshotEvery,
+	}
+	if err := t.loadSnapshot(); err != nil {
+		return nil, fmt.Errorf("load %s snapshot: %w", name, err)
+	}
+	if err := t.replayWal(); err != nil {
+		return nil, fmt.Errorf("replay %s log: %w", name, err)
+	}
+
+	wal, err := os.OpenFile(t.walFp, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := wal.Stat()
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+	t.wal, t.walSize = wal, info.Size()
+
+	return t, nil
+}
+
+func (t *fileTable) decode(data []byte) (ObjectsModel, error) {
+	obj := t.newModel()
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (t *fileTable) loadSnapshot() error {
+	data, err := os.ReadFile(t.snapshotFp)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var objects []json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return err
+	}
+	for _, raw := range objects {
+		obj, err := t.decode(raw)
+		if err != nil {
+			return err
+		}
+		t.mem.put(obj)
+	}
+	return nil
+}
+
+// replayWal applies logged changes on top of the snapshot. A last record
+// without its newline was cut by a crash while writing and is dropped, any
+// other broken record fails.
+func (t *fileTable) replayWal() error {
+	data, err := os.ReadFile(t.walFp)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			logger.Warn("Dropped incomplete log record", "fp", t.walFp, "offset", offset)
+			return os.Truncate(t.walFp, int64(offset))
+		}
+
+		line := data[offset : offset+end]
+		if err := t.replay(line); err != nil {
+			return fmt.Errorf("record at offset %d: %w", offset, err)
+		}
+		offset += end + 1
+		t.records++
+	}
+	return nil
+}
+
+// replay applies one record. Records may be replayed twice when a crash
+// happened between writing a snapshot and truncating the log, so putting an
+// existing or deleting a missing object isn't an error.
+func (t *fileTable) replay(line []byte) error {
+	var rec walRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return err
+	}
+
+	switch rec.Op {
+	case walPut:
+		obj, err := t.decode(rec.Object)
+		if err != nil {
+			return err
+		}
+		t.mem.put(obj)
+	case walDelete:
+		t.mem.remove(rec.ID)
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+	return nil
+}
+
+// append logs rec. A failed write or sync is cut off the log again, so a
+// partly written record isn't followed by the next one. When that fails
+// too the table stops taking writes.
+func (t *fileTable) append(rec walRecord) error {
+	if t.broken != nil {
+		return fmt.Errorf("log %v unusable after failed write: %w", t.walFp, t.broken)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	_, err = t.wal.Write(data)
+	if err == nil {
+		err = t.wal.Sync()
+	}
+	if err != nil {
+		if terr := t.wal.Truncate(t.walSize); terr != nil {
+			t.broken = errors.Join(err, terr)
+			logger.Error("Failed undo log write, table is read-only", "fp", t.walFp, "error", t.broken)
+		}
+		return err
+	}
+
+	t.walSize += int64(len(data))
+	t.records++
+	return nil
+}
+
+// compact writes all objects to a new snapshot and empties the log. A failed
+// compaction is only logged, the log still holds every change.
+func (t *fileTable) compact() {
+	if t.records < t.snapshotEvery {
+		return
+	}
+	if err := t.writeSnapshot(); err != nil {
+		logger.Warn("Failed write snapshot", "fp", t.snapshotFp, "error", err)
+		return
+	}
+	if err := t.wal.Truncate(0); err != nil {
+		logger.Warn("Failed truncate log", "fp", t.walFp, "error", err)
+		return
+	}
+	t.walSize, t.records = 0, 0
+}
+
+func (t *fileTable) writeSnapshot() error {
+	objects, _ := t.mem.List()
+	sort.Slice(objects, func(i, j int) bool {
+		a, b := objects[i].getID(), objects[j].getID()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+
+	tmp := t.snapshotFp + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.snapshotFp)
+}
+
+func (t *fileTable) put(obj ObjectsModel) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if err := t.append(walRecord{Op: walPut, ID: obj.getID(), Object: data}); err != nil {
+		return err
+	}
+
+	t.mem.put(obj)
+	t.compact()
+	return nil
+}
+
+func (t *fileTable) List() ([]ObjectsModel, error) {
+	return t.mem.List()
+}
+
+func (t *fileTable) Get(id ObjectID) (ObjectsModel, error) {
+	return t.mem.Get(id)
+}
+
+func (t *fileTable) Create(obj ObjectsModel) error {
+	obj.setDefaults()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := t.mem.Get(obj.getID()); err == nil {
+		return NewAlreadyExistsError(t.mem.name, obj.getID())
+	}
+	return t.putVersion(obj, 1)
+}
+
+func (t *fileTable) Update(obj ObjectsModel) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stored, err := t.mem.Get(obj.getID())
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(t.mem.name, stored, obj.version()); err != nil {
+		return err
+	}
+	return t.putVersion(obj, stored.version()+1)
+}
+
+// putVersion stores obj at version, obj keeps its old version when storing
+// fails.
+func (t *fileTable) putVersion(obj ObjectsModel, version int64) error {
+	old := obj.version()
+	obj.setVersion(version)
+	if err := t.put(obj); err != nil {
+		obj.setVersion(old)
+		return err
+	}
+	return nil
+}
+
+func (t *fileTable) Delete(id ObjectID, version int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stored, err := t.mem.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(t.mem.name, stored, version); err != nil {
+		return err
+	}
+	if err := t.append(walRecord{Op: walDelete, ID: id}); err != nil {
+		return err
+	}
+
+	t.mem.remove(id)
+	t.compact()
+	return nil
+}
+
+func (t *fileTable) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return errors.Join(t.wal.Sync(), t.wal.Close())
+}
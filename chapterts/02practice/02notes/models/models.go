@@ -2,13 +2,18 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"reflect"
+	"sync"
 
 	"github.com/google/uuid"
 )
 
 type ObjectID uuid.UUID
 
+var objectIDType = reflect.TypeFor[ObjectID]()
+
 func (id ObjectID) MarshalJSON() ([]byte, error) {
 	return json.Marshal(uuid.UUID(id).String())
 }
@@ -16,18 +21,18 @@ func (id ObjectID) MarshalJSON() ([]byte, error) {
 func (id *ObjectID) UnmarshalJSON(data []byte) error {
 	var s string
 	if err := json.Unmarshal(data, &s); err != nil {
-		return err
+		return &json.UnmarshalTypeError{Value: string(data), Type: objectIDType}
 	}
 	parsed, err := uuid.Parse(s)
 	if err != nil {
-		return err
+		return &json.UnmarshalTypeError{Value: "string", Type: objectIDType}
 	}
 	*id = ObjectID(parsed)
 	return nil
 }
 
-func (id *ObjectID) String() string {
-	return uuid.UUID(*id).String()
+func (id ObjectID) String() string {
+	return uuid.UUID(id).String()
 }
 
 type IDGenerator interface {
@@ -39,35 +44,107 @@ type ModelName string
 type ModelsRepositry struct {
 	db          map[ModelName]objectsStorage
 	idGenerator IDGenerator
+	relations   []Relation
+	indexes     map[Relation]*relationIndex
+	tokens      *tokenIndex
+	// mu is held exclusively while deleting referenced objects, so no
+	// reference to them is checked or written meanwhile.
+	mu sync.RWMutex
 }
 
 type objectsStorage interface {
 	List() ([]ObjectsModel, error)
 	Create(ObjectsModel) error
 	Get(ObjectID) (ObjectsModel, error)
+	// Update and Delete fail with VersionConflictError unless the version
+	// is 0 or the one of the stored object. Update takes it from the object.
 	Update(ObjectsModel) error
-	Delete(ObjectID) error
+	Delete(id ObjectID, version int64) error
 }
 
 type ObjectsModel interface {
 	getID() ObjectID
 	SetID(*ObjectID)
 	setDefaults()
+	// fields returns values by JSON name for sorting and filtering lists.
+	fields() map[string]any
+	// keepManaged copies fields clients can't change from the stored object.
+	keepManaged(stored ObjectsModel)
+	version() int64
+	setVersion(int64)
 }
 
-var ModelsToRegister = []ModelName{noteModelName, authorModelName}
+// ModelsToRegister are stored by the repository, ModelsToServe are also
+// served by the generic object routes.
+var (
+	ModelsToRegister = []ModelName{noteModelName, authorModelName, tokenModelName}
+	ModelsToServe    = []ModelName{noteModelName, authorModelName}
+)
+
+type repositoryConfig struct {
+	dir           string
+	snapshotEvery int
+	relations     []Relation
+}
 
-func NewModelsRepository(idGenerator IDGenerator, models []ModelName) *ModelsRepositry {
-	db := make(map[ModelName]objectsStorage, 0)
+type RepositoryOption func(*repositoryConfig)
 
-	for _, m := range models {
-		db[m] = newTable(m)
+// WithFileStorage keeps objects in dir, so they survive restarts. Without
+// it objects are only kept in memory.
+func WithFileStorage(dir string) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.dir = dir
 	}
+}
 
-	return &ModelsRepositry{
-		db:          db,
+func NewModelsRepository(idGenerator IDGenerator, models []ModelName, opts ...RepositoryOption) (*ModelsRepositry, error) {
+	cfg := repositoryConfig{snapshotEvery: defaultSnapshotEvery, relations: DefaultRelations}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := validateRelations(cfg.relations, models); err != nil {
+		return nil, err
+	}
+
+	repository := &ModelsRepositry{
+		db:          make(map[ModelName]objectsStorage, len(models)),
 		idGenerator: idGenerator,
+		relations:   cfg.relations,
+		indexes:     make(map[Relation]*relationIndex, len(cfg.relations)),
+		tokens:      newTokenIndex(),
+	}
+
+	for _, m := range models {
+		if cfg.dir == "" {
+			repository.db[m] = newTable(m)
+			continue
+		}
+
+		t, err := openFileTable(cfg.dir, m, cfg.snapshotEvery)
+		if err != nil {
+			repository.Close()
+			return nil, err
+		}
+		repository.db[m] = t
+	}
+
+	if err := repository.buildIndexes(); err != nil {
+		repository.Close()
+		return nil, err
 	}
+
+	return repository, nil
+}
+
+// Close releases files of the storage, the repository can't be used after.
+func (r *ModelsRepositry) Close() error {
+	var errs []error
+	for _, t := range r.db {
+		if c, ok := t.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
 }
 
 type uuidIdGenerator struct{}
@@ -87,4 +164,17 @@ func (u uuidIdGenerator) Generate() ObjectID {
 var ModelParsers = map[ModelName]func(io.Reader) (ObjectsModel, error){
 	noteModelName:   noteFromBytes,
 	authorModelName: authorFromBytes,
+	tokenModelName:  tokenFromBytes,
+}
+
+// NewObject returns an empty object of the model.
+func NewObject(name ModelName) ObjectsModel {
+	return modelFactories[name]()
+}
+
+// modelFactories return empty models to decode stored objects into.
+var modelFactories = map[ModelName]func() ObjectsModel{
+	noteModelName:   func() ObjectsModel { return &Note{} },
+	authorModelName: func() ObjectsModel { return &Author{} },
+	tokenModelName:  func() ObjectsModel { return &Token{} },
 }
@@ -1,8 +1,6 @@
 package models
 
 import (
-	"encoding/json"
-	"fmt"
 	"io"
 	"time"
 )
@@ -10,6 +8,7 @@ import (
 const noteModelName ModelName = "note"
 
 type Note struct {
+	Versioned
 	ID          *ObjectID  `json:"id"`
 	Name        string     `json:"name"`
 	Description *string    `json:"description"`
@@ -18,14 +17,7 @@ type Note struct {
 }
 
 func noteFromBytes(r io.Reader) (ObjectsModel, error) {
-	var note Note
-	if err := json.NewDecoder(r).Decode(&note); err != nil {
-		return nil, err
-	}
-	if note.Name == "" {
-		return nil, fmt.Errorf("invalid name")
-	}
-	return &note, nil
+	return parseObject(r, noteModelName, &Note{})
 }
 
 func (n *Note) getID() ObjectID {
@@ -42,3 +34,45 @@ func (n *Note) setDefaults() {
 		n.Created = &now
 	}
 }
+
+func (n *Note) fields() map[string]any {
+	return map[string]any{
+		"id":          n.ID,
+		"name":        n.Name,
+		"description": n.Description,
+		"created":     n.Created,
+		"author_id":   n.AuthorId,
+	}
+}
+
+func (n *Note) keepManaged(stored ObjectsModel) {
+	n.Created = stored.(*Note).Created
+}
+
+// AdoptNotes gives notes without an author to author and returns how many
+// it changed. Such notes were stored before notes belonged to authors and
+// are out of reach of every caller until adopted.
+func (r *ModelsRepositry) AdoptNotes(author ObjectID) (int, error) {
+	if _, err := r.db[authorModelName].Get(author); err != nil {
+		return 0, err
+	}
+	op := NewRepositoryOperation(noteModelName, r)
+	notes, err := op.List()
+	if err != nil {
+		return 0, err
+	}
+
+	adopted := 0
+	for _, obj := range notes {
+		n := *obj.(*Note)
+		if n.AuthorId != nil {
+			continue
+		}
+		n.AuthorId = &author
+		if err := op.Update(&n, n.version()); err != nil {
+			return adopted, err
+		}
+		adopted++
+	}
+	return adopted, nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptNotes(t *testing.T) {
+	repository, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister)
+	require.NoError(t, err)
+	authors := NewRepositoryOperation(authorModelName, repository)
+	notes := NewRepositoryOperation(noteModelName, repository)
+
+	owner, adopter := &Author{Username: "owner"}, &Author{Username: "adopter"}
+	require.NoError(t, authors.Create(owner))
+	require.NoError(t, authors.Create(adopter))
+	owned, orphan := &Note{Name: "owned", AuthorId: owner.ID}, &Note{Name: "orphan"}
+	require.NoError(t, notes.Create(owned))
+	require.NoError(t, notes.Create(orphan))
+
+	missing := NewUuidGenerator().Generate()
+	_, err = repository.AdoptNotes(missing)
+	var notExists *NotExistsError
+	assert.True(t, errors.As(err, &notExists))
+
+	n, err := repository.AdoptNotes(*adopter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	got, err := notes.Get(*orphan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, *adopter.ID, *got.(*Note).AuthorId)
+	got, err = notes.Get(*owned.ID)
+	require.NoError(t, err)
+	assert.Equal(t, *owner.ID, *got.(*Note).AuthorId)
+}
@@ -17,20 +17,120 @@ func (op *RepositoryOperation) List() ([]ObjectsModel, error) {
 	return op.table().List()
 }
 
+// Query returns the page of objects selected by q.
+func (op *RepositoryOperation) Query(q ListQuery) (ListPage, error) {
+	p, err := q.plan(modelFactories[op.Name]())
+	if err != nil {
+		return ListPage{}, err
+	}
+
+	objects, err := op.table().List()
+	if err != nil {
+		return ListPage{}, err
+	}
+	return p.page(objects), nil
+}
+
 func (op *RepositoryOperation) Create(m ObjectsModel) error {
 	id := op.repository.idGenerator.Generate()
 	m.SetID(&id)
-	return op.table().Create(m)
+
+	op.repository.mu.RLock()
+	defer op.repository.mu.RUnlock()
+
+	if err := op.repository.checkReferences(op.Name, m); err != nil {
+		return err
+	}
+	return op.repository.write(op.Name, id, m, func() error {
+		return op.table().Create(m)
+	})
 }
 
 func (op *RepositoryOperation) Get(id ObjectID) (ObjectsModel, error) {
 	return op.table().Get(id)
 }
 
-func (op *RepositoryOperation) Update(m ObjectsModel) error {
-	return op.table().Update(m)
+// Update replaces the stored object with m, keeping fields managed by the
+// server like the creation time. It fails unless version is 0 or the
+// version of the stored object.
+func (op *RepositoryOperation) Update(m ObjectsModel, version int64) error {
+	op.repository.mu.RLock()
+	defer op.repository.mu.RUnlock()
+
+	stored, err := op.table().Get(m.getID())
+	if err != nil {
+		return err
+	}
+	m.keepManaged(stored)
+	m.setVersion(version)
+
+	if err := op.repository.checkReferences(op.Name, m); err != nil {
+		return err
+	}
+	return op.repository.write(op.Name, m.getID(), m, func() error {
+		return op.table().Update(m)
+	})
+}
+
+// Delete removes the object and, following relations, the objects deleted
+// with it. Nothing is deleted when a relation restricts it or version isn't
+// 0 or the version of the object.
+func (op *RepositoryOperation) Delete(id ObjectID, version int64) error {
+	if !op.repository.referenced(op.Name) {
+		op.repository.mu.RLock()
+		defer op.repository.mu.RUnlock()
+		return op.repository.write(op.Name, id, nil, func() error {
+			return op.table().Delete(id, version)
+		})
+	}
+
+	op.repository.mu.Lock()
+	defer op.repository.mu.Unlock()
+
+	stored, err := op.table().Get(id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(op.Name, stored, version); err != nil {
+		return err
+	}
+	deletions, err := op.repository.collectDeletions(op.Name, id, nil)
+	if err != nil {
+		return err
+	}
+	for _, d := range deletions {
+		err := op.repository.write(d.name, d.id, nil, func() error {
+			return op.repository.db[d.name].Delete(d.id, 0)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (op *RepositoryOperation) Delete(id ObjectID) error {
-	return op.table().Delete(id)
+// QueryReferencing returns the page of objects referencing the id object
+// through rel, which must start at this model.
+func (op *RepositoryOperation) QueryReferencing(rel Relation, id ObjectID, q ListQuery) (ListPage, error) {
+	p, err := q.plan(modelFactories[op.Name]())
+	if err != nil {
+		return ListPage{}, err
+	}
+	if _, err := op.repository.db[rel.To].Get(id); err != nil {
+		return ListPage{}, err
+	}
+
+	ids := op.repository.indexes[rel].referencing(id)
+	objects := make([]ObjectsModel, 0, len(ids))
+	for _, ref := range ids {
+		// Skips objects changed since the index was read.
+		obj, err := op.table().Get(ref)
+		if err != nil {
+			continue
+		}
+		if current := rel.reference(obj); current != nil && *current == id {
+			objects = append(objects, obj)
+		}
+	}
+	return p.page(objects), nil
 }
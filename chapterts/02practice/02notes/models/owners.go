@@ -0,0 +1,26 @@
+package models
+
+// Ownership declares which author owns the objects of a model.
+type Ownership struct {
+	// Field holds the id of the owning author.
+	Field string
+	// Private objects are only visible to their owner.
+	Private bool
+}
+
+// Owners declares the ownership of every model, objects of a model missing
+// here aren't owned by anyone.
+var Owners = map[ModelName]Ownership{
+	noteModelName:   {Field: "author_id", Private: true},
+	authorModelName: {Field: "id"},
+	tokenModelName:  {Field: "author_id", Private: true},
+}
+
+// OwnerOf returns the id of the author owning obj, nil when nobody does.
+func OwnerOf(name ModelName, obj ObjectsModel) *ObjectID {
+	own, ok := Owners[name]
+	if !ok {
+		return nil
+	}
+	return obj.fields()[own.Field].(*ObjectID)
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PatchError reports a merge patch that isn't a JSON object.
+type PatchError struct {
+	Err error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("invalid merge patch: %v", e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// mergePatch applies patch to target as RFC 7386 describes: objects are
+// merged member by member, null removes a member, anything else replaces.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+func decodeJSONValue(r io.Reader) (any, error) {
+	var v any
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	err := d.Decode(&v)
+	return v, err
+}
+
+// patchAttempts bounds how often a patch without an expected version is
+// merged again because the object changed meanwhile.
+const patchAttempts = 5
+
+// Patch applies the merge patch read from r to the id object and stores the
+// result, which is validated like a new object. It fails unless version is 0
+// or the version of the stored object.
+func (op *RepositoryOperation) Patch(id ObjectID, r io.Reader, version int64) (ObjectsModel, error) {
+	patch, err := decodeJSONValue(r)
+	if err != nil {
+		return nil, &PatchError{err}
+	}
+	if _, ok := patch.(map[string]any); !ok {
+		return nil, &PatchError{fmt.Errorf("not an object")}
+	}
+
+	for attempt := 1; ; attempt++ {
+		obj, err := op.patchOnce(id, patch, version)
+		var conflict *VersionConflictError
+		if version == 0 && attempt < patchAttempts && errors.As(err, &conflict) {
+			continue
+		}
+		return obj, err
+	}
+}
+
+// patchOnce merges patch into the stored object, the update fails when the
+// object changed since it was read.
+func (op *RepositoryOperation) patchOnce(id ObjectID, patch any, version int64) (ObjectsModel, error) {
+	stored, err := op.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(op.Name, stored, version); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	target, err := decodeJSONValue(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return nil, err
+	}
+	obj, err := ModelParsers[op.Name](bytes.NewReader(merged))
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &PatchError{err}
+		}
+		return nil, err
+	}
+	obj.SetID(&id)
+
+	if err := op.Update(obj, stored.version()); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
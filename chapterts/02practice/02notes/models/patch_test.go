@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7386, appendix A.
+	tests := []struct{ target, patch, expected string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		target, err := decodeJSONValue(strings.NewReader(tt.target))
+		require.NoError(t, err)
+		patch, err := decodeJSONValue(strings.NewReader(tt.patch))
+		require.NoError(t, err)
+
+		result, err := json.Marshal(mergePatch(target, patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, tt.expected, string(result), "%s + %s", tt.target, tt.patch)
+	}
+}
+
+func TestOperation_Patch(t *testing.T) {
+	notes, authors := newRelationsRepository(t)
+	author := &Author{Username: "writer"}
+	require.NoError(t, authors.Create(author))
+	description := "details"
+	note := &Note{Name: "draft", Description: &description, AuthorId: author.ID}
+	require.NoError(t, notes.Create(note))
+
+	obj, err := notes.Patch(*note.ID, strings.NewReader(`{"name": "final", "description": null, "created": "2000-01-01T00:00:00Z"}`), 0)
+	require.NoError(t, err)
+	patched := obj.(*Note)
+	assert.Equal(t, "final", patched.Name)
+	assert.Nil(t, patched.Description)
+	assert.Equal(t, *author.ID, *patched.AuthorId)
+	assert.True(t, note.Created.Equal(*patched.Created), "created is managed by the server")
+
+	var patchErr *PatchError
+	for _, body := range []string{`[]`, `{`} {
+		_, err := notes.Patch(*note.ID, strings.NewReader(body), 0)
+		assert.True(t, errors.As(err, &patchErr), body)
+	}
+	var invalid *ValidationError
+	for _, body := range []string{`{"name": null}`, `{"name": 1}`} {
+		_, err := notes.Patch(*note.ID, strings.NewReader(body), 0)
+		assert.True(t, errors.As(err, &invalid), body)
+	}
+
+	var notExists *NotExistsError
+	_, err = notes.Patch(NewUuidGenerator().Generate(), strings.NewReader(`{}`), 0)
+	assert.True(t, errors.As(err, &notExists))
+
+	var fkErr *ForeignKeyError
+	_, err = notes.Patch(*note.ID, strings.NewReader(`{"author_id": "`+NewUuidGenerator().Generate().String()+`"}`), 0)
+	assert.True(t, errors.As(err, &fkErr))
+}
+
+func TestOperation_UpdateKeepsCreated(t *testing.T) {
+	notes, _ := newRelationsRepository(t)
+	note := &Note{Name: "draft"}
+	require.NoError(t, notes.Create(note))
+
+	replacement := &Note{ID: note.ID, Name: "replaced"}
+	require.NoError(t, notes.Update(replacement, 0))
+	require.NotNil(t, replacement.Created)
+	assert.True(t, note.Created.Equal(*replacement.Created))
+}
@@ -0,0 +1,287 @@
+package models
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+
+	defaultSort    = "id"
+	containsSuffix = "_contains"
+)
+
+// ListQuery selects a page of objects. Sort names a field, prefixed with "-"
+// for descending order. Filters map a field to the value it must equal, or
+// "<field>_contains" to a text the field must contain.
+type ListQuery struct {
+	Limit   int
+	Cursor  string
+	Sort    string
+	Filters map[string]string
+}
+
+// QueryFields returns the fields list queries of model name can sort and
+// filter by.
+func QueryFields(name ModelName) map[string]bool {
+	result := map[string]bool{}
+	for field := range NewObject(name).fields() {
+		result[field] = true
+	}
+	return result
+}
+
+type ListPage struct {
+	Results []ObjectsModel
+	// NextCursor continues after the last result, empty on the last page.
+	NextCursor string
+	// Total counts all objects matching the filters.
+	Total int
+}
+
+type QueryError struct {
+	Message string
+}
+
+func newQueryError(format string, args ...any) *QueryError {
+	return &QueryError{fmt.Sprintf(format, args...)}
+}
+
+func (e *QueryError) Error() string {
+	return e.Message
+}
+
+// ParseListQuery reads limit, cursor and sort from values, every other
+// parameter is a filter.
+func ParseListQuery(values url.Values) (ListQuery, error) {
+	q := ListQuery{
+		Limit:   DefaultListLimit,
+		Cursor:  values.Get("cursor"),
+		Sort:    values.Get("sort"),
+		Filters: map[string]string{},
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxListLimit {
+			return ListQuery{}, newQueryError("limit must be between 1 and %d", MaxListLimit)
+		}
+		q.Limit = n
+	}
+
+	for name := range values {
+		switch name {
+		case "limit", "cursor", "sort":
+		default:
+			q.Filters[name] = values.Get(name)
+		}
+	}
+
+	return q, nil
+}
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	idField
+	timeField
+)
+
+// fieldOf returns the kind of a model field value and a key ordering values
+// like the field does. Missing values have an empty key.
+func fieldOf(v any) (fieldKind, string) {
+	switch v := v.(type) {
+	case string:
+		return textField, v
+	case *string:
+		if v == nil {
+			return textField, ""
+		}
+		return textField, *v
+	case *ObjectID:
+		if v == nil {
+			return idField, ""
+		}
+		return idField, v.String()
+	case *time.Time:
+		if v == nil {
+			return timeField, ""
+		}
+		return timeField, v.UTC().Format("2006-01-02T15:04:05.000000000Z")
+	default:
+		panic(fmt.Sprintf("unsupported field type %T", v))
+	}
+}
+
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"id"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+type filter struct {
+	field    string
+	value    string
+	contains bool
+}
+
+type listPlan struct {
+	limit   int
+	sort    string
+	desc    bool
+	filters []filter
+	after   *cursor
+}
+
+// plan checks q against the fields of sample, so unknown fields fail before
+// any object is read. A limit below 1 means DefaultListLimit.
+func (q ListQuery) plan(sample ObjectsModel) (listPlan, error) {
+	fields := sample.fields()
+	p := listPlan{limit: q.Limit, sort: q.Sort}
+
+	if p.limit <= 0 {
+		p.limit = DefaultListLimit
+	}
+
+	if strings.HasPrefix(p.sort, "-") {
+		p.sort, p.desc = p.sort[1:], true
+	}
+	if p.sort == "" {
+		p.sort = defaultSort
+	}
+	if _, ok := fields[p.sort]; !ok {
+		return p, newQueryError("unknown sort field %q", p.sort)
+	}
+
+	for name, value := range q.Filters {
+		f := filter{field: name, value: value}
+		if field, ok := strings.CutSuffix(name, containsSuffix); ok {
+			f.field, f.value, f.contains = field, strings.ToLower(value), true
+		}
+
+		v, ok := fields[f.field]
+		if !ok {
+			return p, newQueryError("unknown filter %q", name)
+		}
+		switch kind, _ := fieldOf(v); {
+		case kind == idField && !f.contains:
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return p, newQueryError("invalid uuid in %q", name)
+			}
+			f.value = id.String()
+		case kind != textField:
+			return p, newQueryError("filter %q is not supported", name)
+		}
+		p.filters = append(p.filters, f)
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil || c.Sort != p.order() {
+			return p, newQueryError("invalid cursor")
+		}
+		p.after = &c
+	}
+
+	return p, nil
+}
+
+// order names the sort like the sort parameter does, with defaults applied.
+func (p listPlan) order() string {
+	if p.desc {
+		return "-" + p.sort
+	}
+	return p.sort
+}
+
+func (p listPlan) matches(obj ObjectsModel) bool {
+	fields := obj.fields()
+	for _, f := range p.filters {
+		_, key := fieldOf(fields[f.field])
+		if f.contains && !strings.Contains(strings.ToLower(key), f.value) {
+			return false
+		}
+		if !f.contains && key != f.value {
+			return false
+		}
+	}
+	return true
+}
+
+func (p listPlan) position(obj ObjectsModel) cursor {
+	_, key := fieldOf(obj.fields()[p.sort])
+	id := obj.getID()
+	return cursor{Key: key, ID: id.String()}
+}
+
+// compare orders by the sort field, then by id, which follows creation
+// order for uuid v7 ids.
+func (p listPlan) compare(a, b cursor) int {
+	c := cmp.Or(cmp.Compare(a.Key, b.Key), cmp.Compare(a.ID, b.ID))
+	if p.desc {
+		return -c
+	}
+	return c
+}
+
+func (p listPlan) page(objects []ObjectsModel) ListPage {
+	type positioned struct {
+		obj ObjectsModel
+		pos cursor
+	}
+
+	matched := make([]positioned, 0, len(objects))
+	for _, obj := range objects {
+		if p.matches(obj) {
+			matched = append(matched, positioned{obj, p.position(obj)})
+		}
+	}
+	slices.SortFunc(matched, func(a, b positioned) int {
+		return p.compare(a.pos, b.pos)
+	})
+
+	page := ListPage{Results: make([]ObjectsModel, 0, p.limit), Total: len(matched)}
+	for _, m := range matched {
+		if p.after != nil && p.compare(m.pos, *p.after) <= 0 {
+			continue
+		}
+		if len(page.Results) == p.limit {
+			last := page.Results[len(page.Results)-1]
+			next := p.position(last)
+			next.Sort = p.order()
+			page.NextCursor = next.encode()
+			break
+		}
+		page.Results = append(page.Results, m.obj)
+	}
+
+	return page
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedNotes creates notes every second of which belongs to the returned
+// author.
+func seedNotes(t *testing.T, op *RepositoryOperation) ([]*Note, ObjectID) {
+	author := &Author{Username: "writer"}
+	require.NoError(t, NewRepositoryOperation(authorModelName, op.repository).Create(author))
+
+	notes := []*Note{}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Groceries", "Gym plan", "Reading list", "Grocery budget", "Trip"} {
+		at := created.Add(time.Duration(i) * time.Hour)
+		note := &Note{Name: name, Created: &at}
+		if i%2 == 0 {
+			note.AuthorId = author.ID
+		}
+		require.NoError(t, op.Create(note))
+		notes = append(notes, note)
+	}
+	return notes, *author.ID
+}
+
+func newTestOperation(t *testing.T) *RepositoryOperation {
+	repository, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister)
+	require.NoError(t, err)
+	return NewRepositoryOperation(noteModelName, repository)
+}
+
+func names(objects []ObjectsModel) []string {
+	result := make([]string, len(objects))
+	for i, obj := range objects {
+		result[i] = obj.(*Note).Name
+	}
+	return result
+}
+
+func TestQuery_Pages(t *testing.T) {
+	op := newTestOperation(t)
+	seedNotes(t, op)
+
+	seen := []string{}
+	q := ListQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		page, err := op.Query(q)
+		require.NoError(t, err)
+		assert.Equal(t, 5, page.Total)
+		seen = append(seen, names(page.Results)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	assert.Equal(t, []string{"Groceries", "Gym plan", "Reading list", "Grocery budget", "Trip"}, seen)
+}
+
+func TestQuery_CursorSurvivesWrites(t *testing.T) {
+	op := newTestOperation(t)
+	notes, _ := seedNotes(t, op)
+
+	page, err := op.Query(ListQuery{Limit: 2, Sort: "-created"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Trip", "Grocery budget"}, names(page.Results))
+
+	require.NoError(t, op.Delete(notes[3].getID(), 0))
+	require.NoError(t, op.Create(&Note{Name: "Newest"}))
+
+	page, err = op.Query(ListQuery{Limit: 2, Sort: "-created", Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Reading list", "Gym plan"}, names(page.Results))
+}
+
+func TestQuery_Filters(t *testing.T) {
+	op := newTestOperation(t)
+	_, author := seedNotes(t, op)
+
+	page, err := op.Query(ListQuery{Limit: 10, Filters: map[string]string{"name_contains": "GROC"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Groceries", "Grocery budget"}, names(page.Results))
+
+	page, err = op.Query(ListQuery{Limit: 10, Sort: "name", Filters: map[string]string{"author_id": author.String()}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Groceries", "Reading list", "Trip"}, names(page.Results))
+	assert.Equal(t, 3, page.Total)
+}
+
+func TestQuery_DefaultLimit(t *testing.T) {
+	op := newTestOperation(t)
+	seedNotes(t, op)
+
+	for _, limit := range []int{0, -1} {
+		page, err := op.Query(ListQuery{Limit: limit})
+		require.NoError(t, err)
+		assert.Len(t, page.Results, 5)
+		assert.Empty(t, page.NextCursor)
+	}
+}
+
+func TestQuery_Invalid(t *testing.T) {
+	op := newTestOperation(t)
+	seedNotes(t, op)
+	sorted, err := op.Query(ListQuery{Limit: 1, Sort: "name"})
+	require.NoError(t, err)
+	require.NotEmpty(t, sorted.NextCursor)
+
+	for name, q := range map[string]ListQuery{
+		"unknown sort":   {Limit: 1, Sort: "size"},
+		"unknown filter": {Limit: 1, Filters: map[string]string{"size": "1"}},
+		"contains on id": {Limit: 1, Filters: map[string]string{"author_id_contains": "1"}},
+		"invalid uuid":   {Limit: 1, Filters: map[string]string{"author_id": "abc"}},
+		"broken cursor":  {Limit: 1, Cursor: "???"},
+		"other sort":     {Limit: 1, Sort: "-created", Cursor: sorted.NextCursor},
+	} {
+		_, err := op.Query(q)
+		var queryErr *QueryError
+		assert.True(t, errors.As(err, &queryErr), name)
+	}
+}
+
+func TestParseListQuery(t *testing.T) {
+	q, err := ParseListQuery(url.Values{"limit": {"5"}, "sort": {"-created"}, "name_contains": {"gym"}})
+	require.NoError(t, err)
+	assert.Equal(t, ListQuery{Limit: 5, Sort: "-created", Filters: map[string]string{"name_contains": "gym"}}, q)
+
+	q, err = ParseListQuery(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultListLimit, q.Limit)
+
+	for _, limit := range []string{"0", "-1", "x", "1001"} {
+		_, err := ParseListQuery(url.Values{"limit": {limit}})
+		assert.Error(t, err, limit)
+	}
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+)
+
+type OnDelete int
+
+const (
+	// Restrict refuses to delete an object while others reference it.
+	Restrict OnDelete = iota
+	// Cascade deletes referencing objects together with the object.
+	Cascade
+)
+
+// Relation declares that Field of From objects holds the id of a To object.
+// An empty field references nothing.
+type Relation struct {
+	From     ModelName
+	Field    string
+	To       ModelName
+	OnDelete OnDelete
+}
+
+var DefaultRelations = []Relation{
+	{From: noteModelName, Field: "author_id", To: authorModelName, OnDelete: Restrict},
+	{From: tokenModelName, Field: "author_id", To: authorModelName, OnDelete: Cascade},
+}
+
+// WithRelations replaces DefaultRelations of the repository.
+func WithRelations(relations ...Relation) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.relations = relations
+	}
+}
+
+func validateRelations(relations []Relation, models []ModelName) error {
+	registered := map[ModelName]bool{}
+	for _, m := range models {
+		registered[m] = true
+	}
+
+	for _, rel := range relations {
+		if !registered[rel.From] || !registered[rel.To] {
+			return fmt.Errorf("relation %s.%s: model not registered", rel.From, rel.Field)
+		}
+		v, ok := modelFactories[rel.From]().fields()[rel.Field]
+		if !ok {
+			return fmt.Errorf("relation %s.%s: unknown field", rel.From, rel.Field)
+		}
+		if kind, _ := fieldOf(v); kind != idField {
+			return fmt.Errorf("relation %s.%s: not an id field", rel.From, rel.Field)
+		}
+	}
+	return nil
+}
+
+// reference returns the id obj holds in the relation field, or nil.
+func (rel Relation) reference(obj ObjectsModel) *ObjectID {
+	return obj.fields()[rel.Field].(*ObjectID)
+}
+
+// relationIndex maps ids of referenced objects to the objects referencing
+// them, so they are found without scanning the table.
+type relationIndex struct {
+	mu   sync.Mutex
+	refs map[ObjectID]map[ObjectID]struct{}
+	of   map[ObjectID]ObjectID
+}
+
+func newRelationIndex() *relationIndex {
+	return &relationIndex{refs: map[ObjectID]map[ObjectID]struct{}{}, of: map[ObjectID]ObjectID{}}
+}
+
+// set records that id references ref, or nothing when ref is nil.
+func (ix *relationIndex) set(id ObjectID, ref *ObjectID) {
+	if old, ok := ix.of[id]; ok {
+		delete(ix.refs[old], id)
+		if len(ix.refs[old]) == 0 {
+			delete(ix.refs, old)
+		}
+		delete(ix.of, id)
+	}
+	if ref == nil {
+		return
+	}
+
+	if ix.refs[*ref] == nil {
+		ix.refs[*ref] = map[ObjectID]struct{}{}
+	}
+	ix.refs[*ref][id] = struct{}{}
+	ix.of[id] = *ref
+}
+
+func (ix *relationIndex) referencing(ref ObjectID) []ObjectID {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ids := make([]ObjectID, 0, len(ix.refs[ref]))
+	for id := range ix.refs[ref] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Relations returns the relations the repository enforces.
+func (r *ModelsRepositry) Relations() []Relation {
+	return r.relations
+}
+
+func (r *ModelsRepositry) buildIndexes() error {
+	for _, rel := range r.relations {
+		objects, err := r.db[rel.From].List()
+		if err != nil {
+			return err
+		}
+
+		ix := newRelationIndex()
+		for _, obj := range objects {
+			ix.set(obj.getID(), rel.reference(obj))
+		}
+		r.indexes[rel] = ix
+	}
+	return r.buildTokenIndex()
+}
+
+// write changes the id object of name with fn, obj is its new state or nil
+// when deleted. Indexes of the object relations, and the token index for
+// tokens, stay locked during fn, so they record changes in the order the
+// table applies them.
+func (r *ModelsRepositry) write(name ModelName, id ObjectID, obj ObjectsModel, fn func() error) error {
+	var indexes []*relationIndex
+	var refs []*ObjectID
+	for _, rel := range r.relations {
+		if rel.From != name {
+			continue
+		}
+		ix := r.indexes[rel]
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+
+		indexes = append(indexes, ix)
+		if obj != nil {
+			refs = append(refs, rel.reference(obj))
+		} else {
+			refs = append(refs, nil)
+		}
+	}
+
+	if name == tokenModelName {
+		r.tokens.mu.Lock()
+		defer r.tokens.mu.Unlock()
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+	for i, ix := range indexes {
+		ix.set(id, refs[i])
+	}
+	if name == tokenModelName {
+		hash := ""
+		if obj != nil {
+			hash = obj.(*Token).Hash
+		}
+		r.tokens.set(id, hash)
+	}
+	return nil
+}
+
+// checkReferences fails when obj references an object that doesn't exist.
+func (r *ModelsRepositry) checkReferences(name ModelName, obj ObjectsModel) error {
+	for _, rel := range r.relations {
+		if rel.From != name {
+			continue
+		}
+		id := rel.reference(obj)
+		if id == nil {
+			continue
+		}
+		if _, err := r.db[rel.To].Get(*id); err != nil {
+			return NewForeignKeyError(name, rel.Field, rel.To, *id)
+		}
+	}
+	return nil
+}
+
+func (r *ModelsRepositry) referenced(name ModelName) bool {
+	for _, rel := range r.relations {
+		if rel.To == name {
+			return true
+		}
+	}
+	return false
+}
+
+type deletion struct {
+	name ModelName
+	id   ObjectID
+}
+
+// collectDeletions lists what deleting the object takes, referencing
+// objects first. It fails on a restricting reference before anything is
+// deleted.
+func (r *ModelsRepositry) collectDeletions(name ModelName, id ObjectID, result []deletion) ([]deletion, error) {
+	for _, rel := range r.relations {
+		if rel.To != name {
+			continue
+		}
+
+		refs := r.indexes[rel].referencing(id)
+		if len(refs) == 0 {
+			continue
+		}
+
+		if rel.OnDelete == Restrict {
+			return nil, NewReferencedError(name, id, rel.From, rel.Field, len(refs))
+		}
+		for _, ref := range refs {
+			var err error
+			if result, err = r.collectDeletions(rel.From, ref, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return append(result, deletion{name, id}), nil
+}
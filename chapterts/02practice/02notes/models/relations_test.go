@@ -0,0 +1,136 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRelationsRepository(t *testing.T, opts ...RepositoryOption) (notes, authors *RepositoryOperation) {
+	repository, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister, opts...)
+	require.NoError(t, err)
+	return NewRepositoryOperation(noteModelName, repository), NewRepositoryOperation(authorModelName, repository)
+}
+
+func TestRelations_ForeignKey(t *testing.T) {
+	notes, authors := newRelationsRepository(t)
+	missing := NewUuidGenerator().Generate()
+
+	var fkErr *ForeignKeyError
+	err := notes.Create(&Note{Name: "orphan", AuthorId: &missing})
+	require.True(t, errors.As(err, &fkErr))
+	assert.Equal(t, "author_id", fkErr.Field)
+	assert.Equal(t, authorModelName, fkErr.Target)
+
+	objects, _ := notes.List()
+	assert.Empty(t, objects, "rejected note must not be stored")
+
+	author := &Author{Username: "writer"}
+	require.NoError(t, authors.Create(author))
+	note := &Note{Name: "owned", AuthorId: author.ID}
+	require.NoError(t, notes.Create(note))
+	require.NoError(t, notes.Create(&Note{Name: "anonymous"}))
+
+	err = notes.Update(&Note{ID: note.ID, Name: "moved", AuthorId: &missing}, 0)
+	assert.True(t, errors.As(err, &fkErr))
+	got, _ := notes.Get(*note.ID)
+	assert.Equal(t, "owned", got.(*Note).Name)
+}
+
+func TestRelations_Restrict(t *testing.T) {
+	notes, authors := newRelationsRepository(t)
+	author := &Author{Username: "writer"}
+	require.NoError(t, authors.Create(author))
+	note := &Note{Name: "owned", AuthorId: author.ID}
+	require.NoError(t, notes.Create(note))
+
+	var refErr *ReferencedError
+	err := authors.Delete(*author.ID, 0)
+	require.True(t, errors.As(err, &refErr))
+	assert.Equal(t, 1, refErr.Count)
+	assert.Equal(t, noteModelName, refErr.By)
+
+	require.NoError(t, notes.Delete(*note.ID, 0))
+	assert.NoError(t, authors.Delete(*author.ID, 0))
+
+	var notExists *NotExistsError
+	assert.True(t, errors.As(authors.Delete(*author.ID, 0), &notExists))
+}
+
+func TestRelations_Cascade(t *testing.T) {
+	notes, authors := newRelationsRepository(t, WithRelations(Relation{
+		From: noteModelName, Field: "author_id", To: authorModelName, OnDelete: Cascade,
+	}))
+	author, other := &Author{Username: "writer"}, &Author{Username: "other"}
+	require.NoError(t, authors.Create(author))
+	require.NoError(t, authors.Create(other))
+	for _, a := range []*Author{author, author, other} {
+		require.NoError(t, notes.Create(&Note{Name: "note", AuthorId: a.ID}))
+	}
+
+	require.NoError(t, authors.Delete(*author.ID, 0))
+
+	objects, err := notes.List()
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, *other.ID, *objects[0].(*Note).AuthorId)
+}
+
+func TestRelations_Validate(t *testing.T) {
+	for name, rel := range map[string]Relation{
+		"unknown model": {From: "comment", Field: "author_id", To: authorModelName},
+		"unknown field": {From: noteModelName, Field: "owner_id", To: authorModelName},
+		"not an id":     {From: noteModelName, Field: "name", To: authorModelName},
+	} {
+		_, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister, WithRelations(rel))
+		assert.Error(t, err, name)
+	}
+}
+
+func referencingNames(t *testing.T, notes *RepositoryOperation, author *Author) []string {
+	rel := DefaultRelations[0]
+	page, err := notes.QueryReferencing(rel, *author.ID, ListQuery{Limit: 10, Sort: "name"})
+	require.NoError(t, err)
+	return names(page.Results)
+}
+
+func TestRelations_QueryReferencing(t *testing.T) {
+	notes, authors := newRelationsRepository(t)
+	author, other := &Author{Username: "writer"}, &Author{Username: "other"}
+	require.NoError(t, authors.Create(author))
+	require.NoError(t, authors.Create(other))
+
+	first := &Note{Name: "first", AuthorId: author.ID}
+	second := &Note{Name: "second", AuthorId: author.ID}
+	for _, n := range []*Note{first, second, {Name: "elsewhere", AuthorId: other.ID}, {Name: "anonymous"}} {
+		require.NoError(t, notes.Create(n))
+	}
+	assert.Equal(t, []string{"first", "second"}, referencingNames(t, notes, author))
+
+	require.NoError(t, notes.Update(&Note{ID: second.ID, Name: "moved", AuthorId: other.ID}, 0))
+	require.NoError(t, notes.Delete(*first.ID, 0))
+	assert.Empty(t, referencingNames(t, notes, author))
+	assert.Equal(t, []string{"elsewhere", "moved"}, referencingNames(t, notes, other))
+
+	var notExists *NotExistsError
+	_, err := notes.QueryReferencing(DefaultRelations[0], NewUuidGenerator().Generate(), ListQuery{Limit: 10})
+	assert.True(t, errors.As(err, &notExists))
+}
+
+func TestRelations_IndexRebuiltOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	notes, authors := newRelationsRepository(t, WithFileStorage(dir))
+	author := &Author{Username: "writer"}
+	require.NoError(t, authors.Create(author))
+	require.NoError(t, notes.Create(&Note{Name: "kept", AuthorId: author.ID}))
+	require.NoError(t, notes.repository.Close())
+
+	notes, authors = newRelationsRepository(t, WithFileStorage(dir))
+	defer notes.repository.Close()
+	assert.Equal(t, []string{"kept"}, referencingNames(t, notes, author))
+
+	var refErr *ReferencedError
+	assert.True(t, errors.As(authors.Delete(*author.ID, 0), &refErr))
+}
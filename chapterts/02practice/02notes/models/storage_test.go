@@ -0,0 +1,265 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNote(name string) *Note {
+	id := NewUuidGenerator().Generate()
+	return &Note{ID: &id, Name: name}
+}
+
+func openTestTable(t *testing.T, dir string, snapshotEvery int) *fileTable {
+	table, err := openFileTable(dir, noteModelName, snapshotEvery)
+	require.NoError(t, err)
+	t.Cleanup(func() { table.Close() })
+	return table
+}
+
+// testStorageConformance checks the behaviour every objectsStorage must
+// share, so handlers work the same over any backend.
+func testStorageConformance(t *testing.T, newStorage func(t *testing.T) objectsStorage) {
+	t.Run("create and get", func(t *testing.T) {
+		s := newStorage(t)
+		note := newTestNote("first")
+
+		require.NoError(t, s.Create(note))
+		assert.NotNil(t, note.Created, "defaults are set on create")
+
+		got, err := s.Get(note.getID())
+		require.NoError(t, err)
+		assert.Equal(t, "first", got.(*Note).Name)
+
+		var exists *AlreadyExistsError
+		assert.True(t, errors.As(s.Create(note), &exists))
+	})
+
+	t.Run("missing objects", func(t *testing.T) {
+		s := newStorage(t)
+		note := newTestNote("missing")
+
+		var notExists *NotExistsError
+		_, err := s.Get(note.getID())
+		assert.True(t, errors.As(err, &notExists))
+		assert.True(t, errors.As(s.Update(note), &notExists))
+		assert.True(t, errors.As(s.Delete(note.getID(), 0), &notExists))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		s := newStorage(t)
+		note := newTestNote("before")
+		require.NoError(t, s.Create(note))
+
+		updated := &Note{ID: note.ID, Name: "after", Created: note.Created}
+		require.NoError(t, s.Update(updated))
+
+		got, err := s.Get(note.getID())
+		require.NoError(t, err)
+		assert.Equal(t, "after", got.(*Note).Name)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		s := newStorage(t)
+		note := newTestNote("v1")
+		require.NoError(t, s.Create(note))
+		assert.Equal(t, int64(1), note.Version)
+
+		next := &Note{ID: note.ID, Name: "v2", Versioned: Versioned{1}}
+		require.NoError(t, s.Update(next))
+		assert.Equal(t, int64(2), next.Version)
+
+		var conflict *VersionConflictError
+		stale := &Note{ID: note.ID, Name: "stale", Versioned: Versioned{1}}
+		require.True(t, errors.As(s.Update(stale), &conflict))
+		assert.Equal(t, int64(2), conflict.Actual)
+		assert.True(t, errors.As(s.Delete(note.getID(), 1), &conflict))
+
+		got, err := s.Get(note.getID())
+		require.NoError(t, err)
+		assert.Equal(t, "v2", got.(*Note).Name)
+
+		require.NoError(t, s.Update(&Note{ID: note.ID, Name: "v3"}), "version 0 replaces any")
+		require.NoError(t, s.Delete(note.getID(), 3))
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		s := newStorage(t)
+		first, second := newTestNote("first"), newTestNote("second")
+		require.NoError(t, s.Create(first))
+		require.NoError(t, s.Create(second))
+
+		objects, err := s.List()
+		require.NoError(t, err)
+		assert.Len(t, objects, 2)
+
+		require.NoError(t, s.Delete(first.getID(), 0))
+		objects, err = s.List()
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		assert.Equal(t, "second", objects[0].(*Note).Name)
+	})
+}
+
+func TestTable_Conformance(t *testing.T) {
+	testStorageConformance(t, func(t *testing.T) objectsStorage {
+		return newTable(noteModelName)
+	})
+}
+
+func TestFileTable_Conformance(t *testing.T) {
+	testStorageConformance(t, func(t *testing.T) objectsStorage {
+		return openTestTable(t, t.TempDir(), 3)
+	})
+}
+
+func TestFileTable_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	kept, updated, deleted := newTestNote("kept"), newTestNote("before"), newTestNote("deleted")
+
+	table := openTestTable(t, dir, 4)
+	for _, n := range []*Note{kept, updated, deleted} {
+		require.NoError(t, table.Create(n))
+	}
+	require.NoError(t, table.Update(&Note{ID: updated.ID, Name: "after", Created: updated.Created}))
+	require.NoError(t, table.Delete(deleted.getID(), 0))
+	require.NoError(t, table.Close())
+
+	_, err := os.Stat(filepath.Join(dir, "note"+snapshotExt))
+	assert.NoError(t, err, "snapshot written after 4 records")
+
+	reopened := openTestTable(t, dir, 4)
+	objects, err := reopened.List()
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+
+	got, err := reopened.Get(updated.getID())
+	require.NoError(t, err)
+	assert.Equal(t, "after", got.(*Note).Name)
+	assert.Equal(t, int64(2), got.(*Note).Version)
+	assert.True(t, updated.Created.Equal(*got.(*Note).Created))
+
+	_, err = reopened.Get(deleted.getID())
+	assert.Error(t, err)
+}
+
+func TestFileTable_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	table := openTestTable(t, dir, 3)
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, table.Create(newTestNote("note")))
+	}
+
+	assert.Equal(t, 1, table.records)
+	info, err := os.Stat(filepath.Join(dir, "note"+walExt))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+
+	require.NoError(t, table.Close())
+	objects, err := openTestTable(t, dir, 3).List()
+	require.NoError(t, err)
+	assert.Len(t, objects, 7)
+}
+
+func TestFileTable_TruncatedLog(t *testing.T) {
+	dir := t.TempDir()
+	walFp := filepath.Join(dir, "note"+walExt)
+	note := newTestNote("complete")
+
+	table := openTestTable(t, dir, 100)
+	require.NoError(t, table.Create(note))
+	require.NoError(t, table.Close())
+
+	f, err := os.OpenFile(walFp, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"put","id":"0196`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reopened := openTestTable(t, dir, 100)
+	objects, err := reopened.List()
+	require.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	require.NoError(t, reopened.Create(newTestNote("after crash")))
+	require.NoError(t, reopened.Close())
+	objects, err = openTestTable(t, dir, 100).List()
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
+
+func TestFileTable_CorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	walFp := filepath.Join(dir, "note"+walExt)
+	require.NoError(t, os.WriteFile(walFp, []byte("not json\n{}\n"), 0o644))
+
+	_, err := openFileTable(dir, noteModelName, 100)
+	assert.Error(t, err)
+}
+
+// shortWal writes only half of the next record and fails, like a full disk.
+type shortWal struct {
+	walFile
+	fail, failTruncate bool
+}
+
+func (w *shortWal) Write(p []byte) (int, error) {
+	if !w.fail {
+		return w.walFile.Write(p)
+	}
+	w.fail = false
+	n, _ := w.walFile.Write(p[:len(p)/2])
+	return n, syscall.ENOSPC
+}
+
+func (w *shortWal) Truncate(size int64) error {
+	if w.failTruncate {
+		return syscall.EIO
+	}
+	return w.walFile.Truncate(size)
+}
+
+func TestFileTable_FailedAppend(t *testing.T) {
+	dir := t.TempDir()
+	table := openTestTable(t, dir, 100)
+	kept := newTestNote("kept")
+	require.NoError(t, table.Create(kept))
+
+	wal := &shortWal{walFile: table.wal, fail: true}
+	table.wal = wal
+	lost := newTestNote("lost")
+	assert.ErrorIs(t, table.Create(lost), syscall.ENOSPC)
+	assert.Zero(t, lost.Version, "version of an object that wasn't stored")
+
+	wal.fail = true
+	update := &Note{ID: kept.ID, Name: "changed", Versioned: Versioned{Version: 1}}
+	assert.ErrorIs(t, table.Update(update), syscall.ENOSPC)
+	assert.Equal(t, int64(1), update.Version)
+
+	after := newTestNote("after")
+	require.NoError(t, table.Create(after))
+	require.NoError(t, table.Close())
+
+	objects, err := openTestTable(t, dir, 100).List()
+	require.NoError(t, err, "partial records must not stay in the log")
+	assert.Len(t, objects, 2)
+}
+
+func TestFileTable_FailedUndoStopsWrites(t *testing.T) {
+	dir := t.TempDir()
+	table := openTestTable(t, dir, 100)
+	table.wal = &shortWal{walFile: table.wal, fail: true, failTruncate: true}
+
+	assert.ErrorIs(t, table.Create(newTestNote("lost")), syscall.ENOSPC)
+	assert.ErrorIs(t, table.Create(newTestNote("refused")), syscall.EIO)
+	objects, err := table.List()
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
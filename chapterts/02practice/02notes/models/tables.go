@@ -1,62 +1,143 @@
 package models
 
+import "sync"
+
+// tableShards is the number of independently locked parts of a table, so
+// writes of different objects don't wait for each other.
+const tableShards = 16
+
+type shard struct {
+	mu      sync.RWMutex
+	objects map[ObjectID]ObjectsModel
+}
+
+// Table keeps objects in memory and is safe for concurrent use. Stored
+// objects must not be changed in place, Update replaces them.
 type Table struct {
-	name    ModelName
-	storage map[ObjectID]ObjectsModel
+	name   ModelName
+	shards [tableShards]shard
 }
 
 func newTable(name ModelName) *Table {
-	return &Table{name: name, storage: make(map[ObjectID]ObjectsModel)}
+	t := &Table{name: name}
+	for i := range t.shards {
+		t.shards[i].objects = make(map[ObjectID]ObjectsModel)
+	}
+	return t
 }
 
-func (t Table) List() ([]ObjectsModel, error) {
-	result := make([]ObjectsModel, 0)
+// shard picks by the last id byte, which is random for uuid v7 unlike the
+// leading timestamp bytes.
+func (t *Table) shard(id ObjectID) *shard {
+	return &t.shards[id[len(id)-1]%tableShards]
+}
 
-	for _, m := range t.storage {
-		result = append(result, m)
+// List returns a snapshot of all objects, taken with every shard locked so
+// no write is half seen.
+func (t *Table) List() ([]ObjectsModel, error) {
+	for i := range t.shards {
+		t.shards[i].mu.RLock()
+	}
+	defer func() {
+		for i := range t.shards {
+			t.shards[i].mu.RUnlock()
+		}
+	}()
+
+	size := 0
+	for i := range t.shards {
+		size += len(t.shards[i].objects)
+	}
+	result := make([]ObjectsModel, 0, size)
+	for i := range t.shards {
+		for _, m := range t.shards[i].objects {
+			result = append(result, m)
+		}
 	}
 
 	return result, nil
 }
 
-func (t Table) Get(id ObjectID) (ObjectsModel, error) {
-	obj, ok := t.storage[id]
+func (t *Table) Get(id ObjectID) (ObjectsModel, error) {
+	s := t.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[id]
 	if !ok {
 		return nil, NewNotExistsError(t.name, id)
 	}
 	return obj, nil
 }
 
-func (t Table) Delete(id ObjectID) error {
-	_, ok := t.storage[id]
+func (t *Table) Delete(id ObjectID, version int64) error {
+	s := t.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.objects[id]
 	if !ok {
 		return NewNotExistsError(t.name, id)
 	}
+	if err := checkVersion(t.name, stored, version); err != nil {
+		return err
+	}
 
-	delete(t.storage, id)
+	delete(s.objects, id)
 
 	return nil
 }
 
-func (t Table) Update(obj ObjectsModel) error {
-	_, ok := t.storage[obj.getID()]
+func (t *Table) Update(obj ObjectsModel) error {
+	s := t.shard(obj.getID())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.objects[obj.getID()]
 	if !ok {
 		return NewNotExistsError(t.name, obj.getID())
 	}
+	if err := checkVersion(t.name, stored, obj.version()); err != nil {
+		return err
+	}
 
-	t.storage[obj.getID()] = obj
+	obj.setVersion(stored.version() + 1)
+	s.objects[obj.getID()] = obj
 
 	return nil
 }
 
-func (t Table) Create(obj ObjectsModel) error {
+func (t *Table) Create(obj ObjectsModel) error {
 	obj.setDefaults()
 
-	_, ok := t.storage[obj.getID()]
+	s := t.shard(obj.getID())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.objects[obj.getID()]
 	if !ok {
-		t.storage[obj.getID()] = obj
+		obj.setVersion(1)
+		s.objects[obj.getID()] = obj
 		return nil
 	}
 
 	return NewAlreadyExistsError(t.name, obj.getID())
 }
+
+// put stores obj whether it exists or not.
+func (t *Table) put(obj ObjectsModel) {
+	s := t.shard(obj.getID())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[obj.getID()] = obj
+}
+
+// remove drops the object if it exists.
+func (t *Table) remove(id ObjectID) {
+	s := t.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, id)
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConcurrentWrites runs writers and readers over s at once, under -race
+// it reports unsynchronized access.
+func testConcurrentWrites(t *testing.T, s objectsStorage) {
+	const writers, perWriter = 8, 50
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				objects, err := s.List()
+				assert.NoError(t, err)
+				for _, obj := range objects {
+					s.Get(obj.getID())
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWriter; j++ {
+				note := newTestNote("note")
+				assert.NoError(t, s.Create(note))
+				assert.NoError(t, s.Update(&Note{ID: note.ID, Name: "updated", Created: note.Created}))
+				if j%5 == 0 {
+					assert.NoError(t, s.Delete(note.getID(), 0))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	objects, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, objects, writers*perWriter*4/5)
+	for _, obj := range objects {
+		assert.Equal(t, "updated", obj.(*Note).Name)
+	}
+}
+
+func TestTable_ConcurrentWrites(t *testing.T) {
+	testConcurrentWrites(t, newTable(noteModelName))
+}
+
+func TestFileTable_ConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	table := openTestTable(t, dir, 64)
+	testConcurrentWrites(t, table)
+	require.NoError(t, table.Close())
+
+	objects, err := openTestTable(t, dir, 64).List()
+	require.NoError(t, err)
+	assert.Len(t, objects, 320)
+}
+
+func TestTable_ConcurrentCreateSameID(t *testing.T) {
+	table := newTable(noteModelName)
+	id := NewUuidGenerator().Generate()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if table.Create(&Note{ID: &id, Name: "same"}) == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+}
+
+// mutexTable reproduces a table guarded by a single lock, to compare it
+// with the sharded one.
+type mutexTable struct {
+	mu      sync.RWMutex
+	objects map[ObjectID]ObjectsModel
+}
+
+func (m *mutexTable) Get(id ObjectID) (ObjectsModel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.objects[id], nil
+}
+
+func (m *mutexTable) Create(obj ObjectsModel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[obj.getID()] = obj
+	return nil
+}
+
+func benchmarkMixedLoad(b *testing.B, get func(ObjectID) (ObjectsModel, error), create func(ObjectsModel) error) {
+	ids := []ObjectID{}
+	for i := 0; i < 1000; i++ {
+		note := newTestNote("note")
+		create(note)
+		ids = append(ids, note.getID())
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if i%10 == 0 {
+				create(newTestNote("note"))
+			} else {
+				get(ids[i%len(ids)])
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkTable_ParallelMixed(b *testing.B) {
+	b.Run("sharded", func(b *testing.B) {
+		table := newTable(noteModelName)
+		benchmarkMixedLoad(b, table.Get, table.Create)
+	})
+	b.Run("single_lock", func(b *testing.B) {
+		table := &mutexTable{objects: map[ObjectID]ObjectsModel{}}
+		benchmarkMixedLoad(b, table.Get, table.Create)
+	})
+}
+
+func BenchmarkTable_ListWhileWriting(b *testing.B) {
+	table := newTable(noteModelName)
+	for i := 0; i < 1000; i++ {
+		table.Create(newTestNote("note"))
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				table.Create(newTestNote("note"))
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			table.List()
+		}
+	})
+}
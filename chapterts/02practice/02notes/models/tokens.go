@@ -0,0 +1,178 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const tokenModelName ModelName = "token"
+
+const (
+	// TokenModel stores the tokens, AccountModel is the model they are
+	// issued for.
+	TokenModel   = tokenModelName
+	AccountModel = authorModelName
+)
+
+// Token authenticates requests as its author. Only the hash of the secret
+// is stored, the secret itself is returned once when the token is issued.
+type Token struct {
+	Versioned
+	ID       *ObjectID  `json:"id"`
+	Name     string     `json:"name"`
+	AuthorId *ObjectID  `json:"author_id"`
+	Hash     string     `json:"hash,omitempty"`
+	Created  *time.Time `json:"created"`
+}
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// tokenSecretPrefix marks secrets of the notes API, so they are told apart
+// from other credentials.
+const tokenSecretPrefix = "nt_"
+
+func tokenFromBytes(r io.Reader) (ObjectsModel, error) {
+	return parseObject(r, tokenModelName, &Token{})
+}
+
+func (t *Token) getID() ObjectID {
+	return *t.ID
+}
+
+func (t *Token) SetID(id *ObjectID) {
+	t.ID = id
+}
+
+func (t *Token) setDefaults() {
+	if t.Created == nil {
+		now := time.Now()
+		t.Created = &now
+	}
+}
+
+func (t *Token) fields() map[string]any {
+	return map[string]any{
+		"id":        t.ID,
+		"name":      t.Name,
+		"author_id": t.AuthorId,
+		"created":   t.Created,
+	}
+}
+
+func (t *Token) keepManaged(stored ObjectsModel) {
+	s := stored.(*Token)
+	t.Created, t.Hash = s.Created, s.Hash
+}
+
+// Redacted returns a copy of t without the hash, to be shown to clients.
+func (t *Token) Redacted() *Token {
+	c := *t
+	c.Hash = ""
+	return &c
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueToken stores t for the author it names and returns its secret.
+func (r *ModelsRepositry) IssueToken(t *Token) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := tokenSecretPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	t.Hash = hashToken(secret)
+	if err := NewRepositoryOperation(tokenModelName, r).Create(t); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Authenticate returns the author a token secret was issued for, it fails
+// with ErrInvalidToken for unknown or revoked secrets.
+func (r *ModelsRepositry) Authenticate(secret string) (*Author, error) {
+	id, ok := r.tokens.lookup(hashToken(secret))
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	var notExists *NotExistsError
+	obj, err := r.db[tokenModelName].Get(id)
+	if errors.As(err, &notExists) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := obj.(*Token)
+	if t.AuthorId == nil {
+		return nil, ErrInvalidToken
+	}
+
+	author, err := r.db[authorModelName].Get(*t.AuthorId)
+	if errors.As(err, &notExists) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return author.(*Author), nil
+}
+
+// tokenIndex maps hashes of token secrets to token ids, so requests are
+// authenticated without scanning the table.
+type tokenIndex struct {
+	mu     sync.Mutex
+	ids    map[string]ObjectID
+	hashes map[ObjectID]string
+}
+
+func newTokenIndex() *tokenIndex {
+	return &tokenIndex{ids: map[string]ObjectID{}, hashes: map[ObjectID]string{}}
+}
+
+// set records the hash of token id, or forgets the token when hash is
+// empty.
+func (ix *tokenIndex) set(id ObjectID, hash string) {
+	if old, ok := ix.hashes[id]; ok {
+		delete(ix.ids, old)
+		delete(ix.hashes, id)
+	}
+	if hash == "" {
+		return
+	}
+	ix.ids[hash] = id
+	ix.hashes[id] = hash
+}
+
+func (ix *tokenIndex) lookup(hash string) (ObjectID, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	id, ok := ix.ids[hash]
+	return id, ok
+}
+
+func (r *ModelsRepositry) buildTokenIndex() error {
+	table, ok := r.db[tokenModelName]
+	if !ok {
+		return nil
+	}
+	tokens, err := table.List()
+	if err != nil {
+		return err
+	}
+	for _, obj := range tokens {
+		r.tokens.set(obj.getID(), obj.(*Token).Hash)
+	}
+	return nil
+}
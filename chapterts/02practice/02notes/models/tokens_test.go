@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens_IssueAndAuthenticate(t *testing.T) {
+	repository, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister)
+	require.NoError(t, err)
+	authors := NewRepositoryOperation(authorModelName, repository)
+
+	author := &Author{Username: "holder"}
+	require.NoError(t, authors.Create(author))
+	token := &Token{Name: "cli", AuthorId: author.ID}
+	secret, err := repository.IssueToken(token)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, tokenSecretPrefix))
+	assert.Equal(t, hashToken(secret), token.Hash)
+	assert.Empty(t, token.Redacted().Hash)
+	assert.NotEmpty(t, token.Hash, "Redacted copies the token")
+
+	got, err := repository.Authenticate(secret)
+	require.NoError(t, err)
+	assert.Equal(t, *author.ID, *got.ID)
+
+	_, err = repository.Authenticate(secret + "x")
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	missing := NewUuidGenerator().Generate()
+	_, err = repository.IssueToken(&Token{AuthorId: &missing})
+	var fkErr *ForeignKeyError
+	assert.True(t, errors.As(err, &fkErr))
+
+	require.NoError(t, authors.Delete(*author.ID, 0))
+	_, err = repository.Authenticate(secret)
+	assert.True(t, errors.Is(err, ErrInvalidToken), "tokens are deleted with their author")
+	_, err = NewRepositoryOperation(tokenModelName, repository).Get(*token.ID)
+	var notExists *NotExistsError
+	assert.True(t, errors.As(err, &notExists))
+}
+
+func TestTokens_OnlyHashIsStored(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister, WithFileStorage(dir))
+	require.NoError(t, err)
+
+	author := &Author{Username: "holder"}
+	require.NoError(t, NewRepositoryOperation(authorModelName, repository).Create(author))
+	secret, err := repository.IssueToken(&Token{AuthorId: author.ID})
+	require.NoError(t, err)
+	require.NoError(t, repository.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "token.wal"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), hashToken(secret))
+	assert.NotContains(t, string(data), secret)
+
+	repository, err = NewModelsRepository(NewUuidGenerator(), ModelsToRegister, WithFileStorage(dir))
+	require.NoError(t, err)
+	defer repository.Close()
+	got, err := repository.Authenticate(secret)
+	require.NoError(t, err)
+	assert.Equal(t, *author.ID, *got.ID)
+}
+
+func TestTokens_IndexFollowsWrites(t *testing.T) {
+	repository, err := NewModelsRepository(NewUuidGenerator(), ModelsToRegister)
+	require.NoError(t, err)
+	author := &Author{Username: "holder"}
+	require.NoError(t, NewRepositoryOperation(authorModelName, repository).Create(author))
+
+	token := &Token{Name: "cli", AuthorId: author.ID}
+	secret, err := repository.IssueToken(token)
+	require.NoError(t, err)
+	id, ok := repository.tokens.lookup(hashToken(secret))
+	require.True(t, ok)
+	assert.Equal(t, *token.ID, id)
+
+	require.NoError(t, NewRepositoryOperation(tokenModelName, repository).Delete(*token.ID, 0))
+	_, ok = repository.tokens.lookup(hashToken(secret))
+	assert.False(t, ok, "revoked tokens leave the index")
+	assert.Empty(t, repository.tokens.hashes)
+	_, err = repository.Authenticate(secret)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
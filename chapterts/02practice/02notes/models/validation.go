@@ -0,0 +1,186 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError describes a rule a field of an object breaks.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rule an object breaks.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid object: " + strings.Join(messages, ", ")
+}
+
+// ParseError reports a body which isn't a JSON object.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid json: %v", e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Rule checks a field value as returned by fields, nil when it passes.
+type Rule func(field string, v any) *FieldError
+
+// text returns the value of a text field, ok is false when it's missing.
+func text(v any) (s string, ok bool) {
+	switch v := v.(type) {
+	case string:
+		return v, v != ""
+	case *string:
+		if v == nil {
+			return "", false
+		}
+		return *v, true
+	}
+	return "", false
+}
+
+func Required() Rule {
+	return func(field string, v any) *FieldError {
+		if s, ok := text(v); !ok || strings.TrimSpace(s) == "" {
+			return &FieldError{field, "required", "must not be empty"}
+		}
+		return nil
+	}
+}
+
+func MaxLength(n int) Rule {
+	return func(field string, v any) *FieldError {
+		if s, _ := text(v); utf8.RuneCountInString(s) > n {
+			return &FieldError{field, "max_length", fmt.Sprintf("must be at most %d characters", n)}
+		}
+		return nil
+	}
+}
+
+// Format requires a present value to match re, described by name in the
+// error message.
+func Format(name string, re *regexp.Regexp) Rule {
+	return func(field string, v any) *FieldError {
+		if s, ok := text(v); ok && !re.MatchString(s) {
+			return &FieldError{field, "format", "must be " + name}
+		}
+		return nil
+	}
+}
+
+type fieldRules struct {
+	field string
+	rules []Rule
+}
+
+var usernameFormat = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// modelRules declare the rules objects of each model must pass, in the
+// order errors are reported.
+var modelRules = map[ModelName][]fieldRules{
+	noteModelName: {
+		{"name", []Rule{Required(), MaxLength(200)}},
+		{"description", []Rule{MaxLength(10000)}},
+	},
+	authorModelName: {
+		{"username", []Rule{Required(), MaxLength(64), Format("letters, digits, '_', '.' or '-'", usernameFormat)}},
+		{"firstname", []Rule{MaxLength(100)}},
+		{"secondname", []Rule{MaxLength(100)}},
+	},
+	tokenModelName: {
+		{"name", []Rule{MaxLength(100)}},
+	},
+}
+
+// Validate checks obj against the rules of its model.
+func Validate(name ModelName, obj ObjectsModel) error {
+	if errs := validate(name, obj, nil); len(errs) > 0 {
+		return &ValidationError{errs}
+	}
+	return nil
+}
+
+// validate returns the first broken rule of every field not in skip.
+func validate(name ModelName, obj ObjectsModel, skip map[string]bool) []FieldError {
+	fields := obj.fields()
+	var errs []FieldError
+	for _, fr := range modelRules[name] {
+		if skip[fr.field] {
+			continue
+		}
+		for _, rule := range fr.rules {
+			if fe := rule(fr.field, fields[fr.field]); fe != nil {
+				errs = append(errs, *fe)
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// parseObject decodes r into obj and validates it. Members are decoded one
+// by one, so a value of a wrong type is reported as an error of its field.
+func parseObject(r io.Reader, name ModelName, obj ObjectsModel) (ObjectsModel, error) {
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&members); err != nil {
+		return nil, &ParseError{err}
+	}
+	if members == nil {
+		return nil, &ParseError{errors.New("not an object")}
+	}
+
+	var errs []FieldError
+	failed := map[string]bool{}
+	for _, field := range slices.Sorted(maps.Keys(members)) {
+		member, _ := json.Marshal(map[string]json.RawMessage{field: members[field]})
+		if err := json.Unmarshal(member, obj); err != nil {
+			errs = append(errs, FieldError{field, "type", "must be " + typeName(err)})
+			failed[field] = true
+		}
+	}
+
+	errs = append(errs, validate(name, obj, failed)...)
+	if len(errs) > 0 {
+		return nil, &ValidationError{errs}
+	}
+	return obj, nil
+}
+
+func typeName(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &timeErr):
+		return "an RFC 3339 time"
+	case !errors.As(err, &typeErr):
+		return "valid"
+	case typeErr.Type == objectIDType:
+		return "a uuid"
+	default:
+		return "a " + typeErr.Type.String()
+	}
+}
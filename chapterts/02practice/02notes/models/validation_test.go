@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	empty, blank, long := "", "  ", "ab€d"
+	lower := regexp.MustCompile(`^[a-z]+$`)
+
+	tests := []struct {
+		rule Rule
+		v    any
+		code string
+	}{
+		{Required(), "x", ""},
+		{Required(), "", "required"},
+		{Required(), (*string)(nil), "required"},
+		{Required(), &blank, "required"},
+		{MaxLength(4), &long, ""},
+		{MaxLength(3), &long, "max_length"},
+		{MaxLength(3), (*string)(nil), ""},
+		{Format("lowercase", lower), "abc", ""},
+		{Format("lowercase", lower), "aBc", "format"},
+		{Format("lowercase", lower), &empty, "format"},
+		{Format("lowercase", lower), (*string)(nil), ""},
+	}
+
+	for i, tt := range tests {
+		fe := tt.rule("field", tt.v)
+		if tt.code == "" {
+			assert.Nil(t, fe, i)
+			continue
+		}
+		require.NotNil(t, fe, i)
+		assert.Equal(t, FieldError{"field", tt.code, fe.Message}, *fe, i)
+	}
+}
+
+func TestParseObject(t *testing.T) {
+	obj, err := ModelParsers[noteModelName](strings.NewReader(`{"name": "ok", "unknown": 1}`))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", obj.(*Note).Name)
+
+	var invalid *ValidationError
+	_, err = ModelParsers[noteModelName](strings.NewReader(`{"name": ["x"], "created": "yesterday", "author_id": 7}`))
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []FieldError{
+		{"author_id", "type", "must be a uuid"},
+		{"created", "type", "must be an RFC 3339 time"},
+		{"name", "type", "must be a string"},
+	}, invalid.Errors)
+
+	var parseErr *ParseError
+	for _, body := range []string{`{`, `"note"`, `null`} {
+		_, err := ModelParsers[authorModelName](strings.NewReader(body))
+		assert.True(t, errors.As(err, &parseErr), body)
+	}
+}
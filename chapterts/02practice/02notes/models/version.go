@@ -0,0 +1,50 @@
+package models
+
+import "fmt"
+
+// Versioned counts the changes of an object. Every model embeds it, tables
+// set it to 1 on create and increment it on each update.
+type Versioned struct {
+	Version int64 `json:"version"`
+}
+
+func (v *Versioned) version() int64 {
+	return v.Version
+}
+
+func (v *Versioned) setVersion(version int64) {
+	v.Version = version
+}
+
+// VersionOf returns the version of a stored object.
+func VersionOf(obj ObjectsModel) int64 {
+	return obj.version()
+}
+
+// VersionConflictError reports a write expecting another version of the
+// object than the stored one, Expected is 0 when it isn't a single one.
+type VersionConflictError struct {
+	ModelName ModelName
+	ID        ObjectID
+	Expected  int64
+	Actual    int64
+}
+
+func NewVersionConflictError(name ModelName, id ObjectID, expected, actual int64) *VersionConflictError {
+	return &VersionConflictError{name, id, expected, actual}
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.Expected == 0 {
+		return fmt.Sprintf("%s(id=%v) is at version %d", e.ModelName, e.ID, e.Actual)
+	}
+	return fmt.Sprintf("%s(id=%v) is at version %d, not %d", e.ModelName, e.ID, e.Actual, e.Expected)
+}
+
+// checkVersion fails when expected is set and stored is at another version.
+func checkVersion(name ModelName, stored ObjectsModel, expected int64) error {
+	if expected != 0 && expected != stored.version() {
+		return NewVersionConflictError(name, stored.getID(), expected, stored.version())
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"notes/api/models"
+
+	"github.com/google/uuid"
+)
+
+// openRepository opens the objects kept in dataDir, or an empty in memory
+// repository when dataDir is empty.
+func openRepository(dataDir string) (*models.ModelsRepositry, error) {
+	var opts []models.RepositoryOption
+	if dataDir != "" {
+		opts = append(opts, models.WithFileStorage(dataDir))
+	}
+	return models.NewModelsRepository(models.NewUuidGenerator(), models.ModelsToRegister, opts...)
+}
+
+// IssueToken issues a token for an existing author and returns its secret,
+// so authors stored before tokens existed can sign in. The server must not
+// run on dataDir meanwhile.
+func IssueToken(dataDir, authorID string) (string, error) {
+	id, err := uuid.Parse(authorID)
+	if err != nil {
+		return "", err
+	}
+	repository, err := openRepository(dataDir)
+	if err != nil {
+		return "", err
+	}
+	defer repository.Close()
+
+	author := models.ObjectID(id)
+	return repository.IssueToken(&models.Token{Name: "issued", AuthorId: &author})
+}
+
+// AdoptNotes gives notes without an author to an existing author and
+// returns how many it changed. The server must not run on dataDir
+// meanwhile.
+func AdoptNotes(dataDir, authorID string) (int, error) {
+	id, err := uuid.Parse(authorID)
+	if err != nil {
+		return 0, err
+	}
+	repository, err := openRepository(dataDir)
+	if err != nil {
+		return 0, err
+	}
+	defer repository.Close()
+
+	return repository.AdoptNotes(models.ObjectID(id))
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"notes/api/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type callerKey struct{}
+
+// callerOf returns the author authenticated for r, nil on public routes.
+func callerOf(r *http.Request) *models.Author {
+	author, _ := r.Context().Value(callerKey{}).(*models.Author)
+	return author
+}
+
+// authenticate resolves the author of the bearer token of r.
+func authenticate(r *http.Request, repository *models.ModelsRepositry) (*http.Request, error) {
+	scheme, secret, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || secret == "" {
+		return nil, models.ErrInvalidToken
+	}
+
+	author, err := repository.Authenticate(strings.TrimSpace(secret))
+	if err != nil {
+		return nil, err
+	}
+	return r.WithContext(context.WithValue(r.Context(), callerKey{}, author)), nil
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="notes"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   "unauthorized",
+		"message": "a valid bearer token is required",
+	})
+}
+
+func forbidden(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   "forbidden",
+		"message": message,
+	})
+}
+
+// owns reports whether the caller of r owns obj, objects of models without
+// an owner belong to everyone.
+func owns(r *http.Request, name models.ModelName, obj models.ObjectsModel) bool {
+	if _, ok := models.Owners[name]; !ok {
+		return true
+	}
+	owner := models.OwnerOf(name, obj)
+	return owner != nil && *owner == *callerOf(r).ID
+}
+
+// checkAccess responds and returns false unless the caller may read obj, or
+// change it when write is set. Private objects of others are reported as
+// missing.
+func checkAccess(w http.ResponseWriter, r *http.Request, name models.ModelName, obj models.ObjectsModel, write bool) bool {
+	switch {
+	case owns(r, name, obj):
+		return true
+	case models.Owners[name].Private:
+		notFound(w)
+		return false
+	case write:
+		forbidden(w, fmt.Sprintf("the %s belongs to another author", name))
+		return false
+	default:
+		return true
+	}
+}
+
+// checkStored is checkAccess for the stored id object.
+func checkStored(w http.ResponseWriter, r *http.Request, id models.ObjectID, op *models.RepositoryOperation) bool {
+	stored, err := op.Get(id)
+	if err != nil {
+		writeFailed(w, err)
+		return false
+	}
+	return checkAccess(w, r, op.Name, stored, true)
+}
+
+// scope restricts a list query of private objects to those of the caller.
+// It returns false when the query asks for objects of another author.
+func scope(r *http.Request, name models.ModelName, q models.ListQuery) bool {
+	own := models.Owners[name]
+	if !own.Private {
+		return true
+	}
+
+	caller := callerOf(r).ID.String()
+	if v, ok := q.Filters[own.Field]; ok && !strings.EqualFold(v, caller) {
+		return false
+	}
+	q.Filters[own.Field] = caller
+	return true
+}
+
+var errOtherOwner = errors.New("body gives the object to another author")
+
+// claim keeps the owner field of a body the caller writes at the caller,
+// filling it in when missing. A patch leaving the field out keeps the
+// stored owner. Bodies that aren't JSON objects are left to the parser.
+func claim(r *http.Request, name models.ModelName, patch bool) error {
+	own, ok := models.Owners[name]
+	// Ids are never taken from bodies.
+	if !ok || own.Field == "id" {
+		return nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	var object map[string]any
+	if json.Unmarshal(data, &object) != nil || object == nil {
+		return nil
+	}
+
+	caller := *callerOf(r).ID
+	v, present := object[own.Field]
+	switch s, isString := v.(string); {
+	case !present:
+		if patch {
+			return nil
+		}
+	case v == nil:
+		if patch {
+			return errOtherOwner
+		}
+	case !isString:
+		return nil
+	default:
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil
+		}
+		if models.ObjectID(id) != caller {
+			return errOtherOwner
+		}
+	}
+
+	body, err := withField(bytes.NewReader(data), own.Field, caller)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(body)
+	return nil
+}
+
+// claimed runs claim, responding when it fails.
+func claimed(w http.ResponseWriter, r *http.Request, name models.ModelName, patch bool) bool {
+	err := claim(r, name, patch)
+	switch {
+	case errors.Is(err, errOtherOwner):
+		forbidden(w, fmt.Sprintf("a %s can't be given to another author", name))
+		return false
+	case err != nil:
+		internalError(w, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"notes/api/models"
+	"strings"
+)
+
+func etag(obj models.ObjectsModel) string {
+	return fmt.Sprintf(`"%d"`, models.VersionOf(obj))
+}
+
+func setETag(w http.ResponseWriter, obj models.ObjectsModel) {
+	w.Header().Set("ETag", etag(obj))
+}
+
+// parseETags splits an If-Match or If-None-Match header into its entity
+// tags, weak ones keep their W/ prefix.
+func parseETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ifMatchVersion returns the version a write must expect following the
+// If-Match header, 0 when any version will do. A weak tag never matches.
+func ifMatchVersion(r *http.Request, id models.ObjectID, op *models.RepositoryOperation) (int64, error) {
+	tags := parseETags(r.Header.Get("If-Match"))
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	stored, err := op.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	for _, tag := range tags {
+		if tag == "*" {
+			return 0, nil
+		}
+		if tag == etag(stored) {
+			return models.VersionOf(stored), nil
+		}
+	}
+	return 0, models.NewVersionConflictError(op.Name, id, 0, models.VersionOf(stored))
+}
+
+// noneMatch reports whether the If-None-Match header lets the request
+// through for obj, comparing tags weakly.
+func noneMatch(r *http.Request, obj models.ObjectsModel) bool {
+	for _, tag := range parseETags(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag(obj) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"net/http"
+	"notes/api/models"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	objectIDType = reflect.TypeFor[models.ObjectID]()
+	timeType     = reflect.TypeFor[time.Time]()
+	pathParam    = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+// readOnlyFields are managed by the server for every model, hiddenFields
+// are stored but never returned.
+var (
+	readOnlyFields = map[string]bool{"id": true, "version": true}
+	hiddenFields   = map[string]bool{"hash": true}
+)
+
+func schemaName(name models.ModelName) string {
+	return strings.ToUpper(string(name[:1])) + string(name[1:])
+}
+
+func ref(schema string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + schema}
+}
+
+// modelField is a JSON member of a model struct.
+type modelField struct {
+	name   string
+	schema map[string]any
+}
+
+// modelFields lists the JSON members of t, including those of embedded
+// structs, with their schema.
+func modelFields(t reflect.Type) []modelField {
+	var fields []modelField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, modelFields(f.Type)...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, modelField{name, fieldSchema(f.Type)})
+	}
+	return fields
+}
+
+func fieldSchema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		schema := fieldSchema(t.Elem())
+		schema["nullable"] = true
+		return schema
+	}
+
+	switch {
+	case t == objectIDType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": fieldSchema(t.Elem())}
+	default:
+		return map[string]any{"type": "object"}
+	}
+}
+
+func modelType(name models.ModelName) reflect.Type {
+	return reflect.TypeOf(models.NewObject(name)).Elem()
+}
+
+func modelSchema(name models.ModelName) map[string]any {
+	properties := map[string]any{}
+	for _, f := range modelFields(modelType(name)) {
+		if hiddenFields[f.name] {
+			continue
+		}
+		if readOnlyFields[f.name] {
+			f.schema["readOnly"] = true
+		}
+		properties[f.name] = f.schema
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func pageSchema(name models.ModelName) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"results":     map[string]any{"type": "array", "items": ref(schemaName(name))},
+			"next_cursor": map[string]any{"type": "string", "nullable": true, "description": "Cursor of the next page, null on the last page."},
+			"total":       map[string]any{"type": "integer", "description": "Number of objects matching the filters."},
+		},
+	}
+}
+
+var errorSchemas = map[string]any{
+	"Error": map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"error":   map[string]any{"type": "string"},
+			"message": map[string]any{"type": "string"},
+		},
+	},
+	"FieldError": map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"field":   map[string]any{"type": "string"},
+			"code":    map[string]any{"type": "string", "enum": []string{"required", "max_length", "format", "type", "invalid_reference"}},
+			"message": map[string]any{"type": "string"},
+		},
+	},
+	"ValidationError": map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"error":  map[string]any{"type": "string"},
+			"errors": map[string]any{"type": "array", "items": ref("FieldError")},
+		},
+	},
+}
+
+func parameter(name, in, description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          in,
+		"required":    in == "path",
+		"description": description,
+		"schema":      schema,
+	}
+}
+
+// listParameters describes the query of list routes, filters follow the
+// text and id fields of the model.
+func listParameters(name models.ModelName) []any {
+	var sorts []string
+	params := []any{
+		parameter("limit", "query", "Page size.", map[string]any{"type": "integer", "minimum": 1, "maximum": models.MaxListLimit, "default": models.DefaultListLimit}),
+		parameter("cursor", "query", "next_cursor of the previous page.", map[string]any{"type": "string"}),
+	}
+
+	queryable := models.QueryFields(name)
+	for _, f := range modelFields(modelType(name)) {
+		if !queryable[f.name] {
+			continue
+		}
+		sorts = append(sorts, f.name, "-"+f.name)
+		if f.schema["type"] != "string" || f.schema["format"] == "date-time" {
+			continue
+		}
+		params = append(params, parameter(f.name, "query", "Only objects whose "+f.name+" equals the value.", map[string]any{"type": "string"}))
+		if f.schema["format"] != "uuid" {
+			params = append(params, parameter(f.name+"_contains", "query", "Only objects whose "+f.name+" contains the text, ignoring case.", map[string]any{"type": "string"}))
+		}
+	}
+
+	return append(params, parameter("sort", "query", "Field to order by, prefixed with - for descending order.", map[string]any{"type": "string", "enum": sorts, "default": "id"}))
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+func (doc operationDoc) parameters(path string) []any {
+	params := []any{}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, parameter(m[1], "path", "Object id.", map[string]any{"type": "string", "format": "uuid"}))
+	}
+	if doc.response == pageBody {
+		params = append(params, listParameters(doc.model)...)
+	}
+	for _, status := range doc.errors {
+		switch status {
+		case http.StatusPreconditionFailed:
+			params = append(params, parameter("If-Match", "header", "ETags of versions the object must be at, or *.", map[string]any{"type": "string"}))
+		case http.StatusNotModified:
+			params = append(params, parameter("If-None-Match", "header", "ETags of versions not to return again.", map[string]any{"type": "string"}))
+		}
+	}
+	return params
+}
+
+func (doc operationDoc) requestBody() map[string]any {
+	switch doc.request {
+	case objectBody:
+		return map[string]any{"required": true, "content": jsonContent(ref(schemaName(doc.model)))}
+	case mergePatchBody:
+		patch := map[string]any{"schema": ref(schemaName(doc.model))}
+		return map[string]any{
+			"required":    true,
+			"description": "JSON Merge Patch (RFC 7386) applied to the stored object.",
+			"content":     map[string]any{"application/merge-patch+json": patch, "application/json": patch},
+		}
+	}
+	return nil
+}
+
+func (doc operationDoc) responses() map[string]any {
+	success := map[string]any{"description": http.StatusText(doc.status)}
+	switch doc.response {
+	case objectBody:
+		if doc.model == "" {
+			success["content"] = jsonContent(map[string]any{"type": "object"})
+			break
+		}
+		success["content"] = jsonContent(ref(schemaName(doc.model)))
+		success["headers"] = map[string]any{
+			"ETag": map[string]any{"description": "Version of the object.", "schema": map[string]any{"type": "string"}},
+		}
+	case secretBody:
+		success["content"] = jsonContent(map[string]any{"allOf": []any{
+			ref(schemaName(doc.model)),
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"secret": map[string]any{"type": "string", "description": "Bearer token, only returned once."}},
+			},
+		}})
+	case pageBody:
+		success["content"] = jsonContent(ref(schemaName(doc.model) + "Page"))
+	case textBody:
+		success["content"] = map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+
+	if len(doc.headers) > 0 {
+		headers, _ := success["headers"].(map[string]any)
+		if headers == nil {
+			headers = map[string]any{}
+		}
+		for name, description := range doc.headers {
+			headers[name] = map[string]any{"description": description, "schema": map[string]any{"type": "string"}}
+		}
+		success["headers"] = headers
+	}
+
+	errors := doc.errors
+	if !doc.public {
+		errors = append([]int{http.StatusUnauthorized}, errors...)
+	}
+
+	responses := map[string]any{strconv.Itoa(doc.status): success}
+	for _, status := range append(errors, http.StatusInternalServerError) {
+		response := map[string]any{"description": http.StatusText(status)}
+		switch status {
+		case http.StatusNotModified:
+		case http.StatusUnprocessableEntity:
+			response["content"] = jsonContent(ref("ValidationError"))
+		default:
+			response["content"] = jsonContent(ref("Error"))
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	return responses
+}
+
+// openAPI describes every route of rt and the models they serve as an
+// OpenAPI 3 document.
+func (rt *router) openAPI() map[string]any {
+	schemas := map[string]any{}
+	for name, schema := range errorSchemas {
+		schemas[name] = schema
+	}
+	for _, name := range models.ModelsToRegister {
+		schemas[schemaName(name)] = modelSchema(name)
+		schemas[schemaName(name)+"Page"] = pageSchema(name)
+	}
+
+	paths := map[string]any{}
+	for _, r := range rt.routes {
+		if paths[r.path] == nil {
+			paths[r.path] = map[string]any{}
+		}
+		operation := map[string]any{
+			"summary":    r.doc.summary,
+			"parameters": r.doc.parameters(r.path),
+			"responses":  r.doc.responses(),
+		}
+		if body := r.doc.requestBody(); body != nil {
+			operation["requestBody"] = body
+		}
+		if r.doc.public {
+			operation["security"] = []any{}
+		}
+		paths[r.path].(map[string]any)[strings.ToLower(r.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Notes API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearer": []string{}}},
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"notes/api/models"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// handleRelations serves /{to}/{id}/{from} for every relation between
+// served models, listing and creating the objects that reference the id
+// object.
+func handleRelations(rt *router, repository *models.ModelsRepositry) {
+	for _, rel := range repository.Relations() {
+		if !slices.Contains(models.ModelsToServe, rel.From) || !slices.Contains(models.ModelsToServe, rel.To) {
+			continue
+		}
+		op := models.NewRepositoryOperation(rel.From, repository)
+		target := models.NewRepositoryOperation(rel.To, repository)
+		path := fmt.Sprintf("/%s/{id}/%s", rel.To, rel.From)
+
+		rt.handle(http.MethodGet, path, operationDoc{
+			summary:  fmt.Sprintf("List %s objects referencing the %s in %s", rel.From, rel.To, rel.Field),
+			model:    rel.From,
+			response: pageBody,
+			status:   http.StatusOK,
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			if id, ok := pathID(w, r, "id"); ok {
+				listReferencing(w, r, rel, id, op)
+			}
+		})
+		rt.handle(http.MethodPost, path, operationDoc{
+			summary:  fmt.Sprintf("Create a %s referencing the %s in %s", rel.From, rel.To, rel.Field),
+			model:    rel.From,
+			request:  objectBody,
+			response: objectBody,
+			status:   http.StatusOK,
+			errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			if id, ok := pathID(w, r, "id"); ok {
+				createReferencing(w, r, rel, id, op, target)
+			}
+		})
+	}
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (models.ObjectID, bool) {
+	uid, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		badRequest(w, "invalid uuid")
+		return models.ObjectID{}, false
+	}
+	return models.ObjectID(uid), true
+}
+
+func listReferencing(w http.ResponseWriter, r *http.Request, rel models.Relation, id models.ObjectID, op *models.RepositoryOperation) {
+	q, err := models.ParseListQuery(r.URL.Query())
+	if err != nil {
+		queryFailed(w, err)
+		return
+	}
+	if !scope(r, op.Name, q) {
+		writePage(w, models.ListPage{Results: []models.ObjectsModel{}})
+		return
+	}
+
+	page, err := op.QueryReferencing(rel, id, q)
+	if err != nil {
+		var notExists *models.NotExistsError
+		if errors.As(err, &notExists) {
+			notFound(w)
+			return
+		}
+		queryFailed(w, err)
+		return
+	}
+
+	writePage(w, page)
+}
+
+// createReferencing creates the object with its relation field set to id,
+// whatever the body holds there.
+func createReferencing(w http.ResponseWriter, r *http.Request, rel models.Relation, id models.ObjectID, op, target *models.RepositoryOperation) {
+	if _, err := target.Get(id); err != nil {
+		writeFailed(w, err)
+		return
+	}
+
+	body, err := withField(r.Body, rel.Field, id)
+	r.Body.Close()
+	if err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	r.Body = io.NopCloser(body)
+
+	create(w, r, op)
+}
+
+func withField(r io.Reader, field string, value any) (io.Reader, error) {
+	var object map[string]any
+	if err := json.NewDecoder(r).Decode(&object); err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, errors.New("not an object")
+	}
+	object[field] = value
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
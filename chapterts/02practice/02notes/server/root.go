@@ -3,8 +3,8 @@ package server
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
 	"notes/api/models"
 	"os"
@@ -14,49 +14,8 @@ import (
 
 var logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
 
-func rootMux() *http.ServeMux {
-	mux := http.NewServeMux()
-
-	repository := models.NewModelsRepository(models.NewUuidGenerator(), models.ModelsToRegister)
-
-	mux.HandleFunc("/ping", ping)
-
-	for _, name := range models.ModelsToRegister {
-		getPath := func(s string) string {
-			return fmt.Sprintf("/%s%s", name, s)
-		}
-
-		op := models.NewRepositoryOperation(name, repository)
-
-		mux.HandleFunc(getPath("/{id}"), func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			id := r.PathValue("id")
-			switch r.Method {
-			case http.MethodGet:
-				get(w, id, op)
-			case http.MethodDelete:
-				mDelete(w, id, op)
-			case http.MethodPut:
-				update(w, r, id, op)
-			default:
-				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-			}
-		})
-
-		mux.HandleFunc(getPath(""), func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			switch r.Method {
-			case http.MethodGet:
-				list(w, op)
-			case http.MethodPost:
-				create(w, r, op)
-			default:
-				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-			}
-		})
-	}
-
-	return mux
+func rootMux(repository *models.ModelsRepositry) http.Handler {
+	return newRouter(repository)
 }
 
 func internalError(w http.ResponseWriter, err error) {
@@ -76,6 +35,60 @@ func badRequest(w http.ResponseWriter, message string) {
 	})
 }
 
+func unprocessable(w http.ResponseWriter, errs []models.FieldError) {
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":  "validation_failed",
+		"errors": errs,
+	})
+}
+
+func preconditionFailed(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   "precondition_failed",
+		"message": message,
+	})
+}
+
+func conflict(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   "conflict",
+		"message": message,
+	})
+}
+
+// writeFailed responds to a body that can't be parsed or stored.
+func writeFailed(w http.ResponseWriter, err error) {
+	var parseErr *models.ParseError
+	var invalid *models.ValidationError
+	var notExists *models.NotExistsError
+	var foreignKey *models.ForeignKeyError
+	var referenced *models.ReferencedError
+	var versionErr *models.VersionConflictError
+	switch {
+	case errors.As(err, &parseErr):
+		badRequest(w, parseErr.Error())
+	case errors.As(err, &invalid):
+		unprocessable(w, invalid.Errors)
+	case errors.As(err, &notExists):
+		notFound(w)
+	case errors.As(err, &foreignKey):
+		unprocessable(w, []models.FieldError{{
+			Field:   foreignKey.Field,
+			Code:    "invalid_reference",
+			Message: foreignKey.Error(),
+		}})
+	case errors.As(err, &referenced):
+		conflict(w, referenced.Error())
+	case errors.As(err, &versionErr):
+		preconditionFailed(w, versionErr.Error())
+	default:
+		internalError(w, err)
+	}
+}
+
 func notFound(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNotFound)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -83,15 +96,45 @@ func notFound(w http.ResponseWriter) {
 	})
 }
 
-func list(w http.ResponseWriter, op *models.RepositoryOperation) {
-	objects, err := op.List()
+func list(w http.ResponseWriter, r *http.Request, op *models.RepositoryOperation) {
+	q, err := models.ParseListQuery(r.URL.Query())
 	if err != nil {
-		internalError(w, err)
+		queryFailed(w, err)
+		return
+	}
+	if !scope(r, op.Name, q) {
+		writePage(w, models.ListPage{Results: []models.ObjectsModel{}})
+		return
+	}
+
+	page, err := op.Query(q)
+	if err != nil {
+		queryFailed(w, err)
+		return
+	}
+
+	writePage(w, page)
+}
+
+func queryFailed(w http.ResponseWriter, err error) {
+	var queryErr *models.QueryError
+	if errors.As(err, &queryErr) {
+		badRequest(w, queryErr.Message)
 		return
 	}
+	internalError(w, err)
+}
+
+func writePage(w http.ResponseWriter, page models.ListPage) {
+	var next *string
+	if page.NextCursor != "" {
+		next = &page.NextCursor
+	}
 
 	data := map[string]any{
-		"results": objects,
+		"results":     page.Results,
+		"next_cursor": next,
+		"total":       page.Total,
 	}
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		internalError(w, err)
@@ -99,7 +142,7 @@ func list(w http.ResponseWriter, op *models.RepositoryOperation) {
 	}
 }
 
-func get(w http.ResponseWriter, id string, op *models.RepositoryOperation) {
+func get(w http.ResponseWriter, r *http.Request, id string, op *models.RepositoryOperation) {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		badRequest(w, "invalid uuid")
@@ -116,6 +159,15 @@ func get(w http.ResponseWriter, id string, op *models.RepositoryOperation) {
 		internalError(w, err)
 		return
 	}
+	if !checkAccess(w, r, op.Name, obj, false) {
+		return
+	}
+
+	setETag(w, obj)
+	if !noneMatch(r, obj) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
 	if err := json.NewEncoder(w).Encode(obj); err != nil {
 		internalError(w, err)
@@ -123,21 +175,23 @@ func get(w http.ResponseWriter, id string, op *models.RepositoryOperation) {
 	}
 }
 
-func mDelete(w http.ResponseWriter, id string, op *models.RepositoryOperation) {
+func mDelete(w http.ResponseWriter, r *http.Request, id string, op *models.RepositoryOperation) {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		badRequest(w, "invalid uuid")
 		return
 	}
+	oid := models.ObjectID(uid)
+	if !checkStored(w, r, oid, op) {
+		return
+	}
 
-	err = op.Delete(models.ObjectID(uid))
+	version, err := ifMatchVersion(r, oid, op)
+	if err == nil {
+		err = op.Delete(oid, version)
+	}
 	if err != nil {
-		var notExists *models.NotExistsError
-		if errors.As(err, &notExists) {
-			notFound(w)
-			return
-		}
-		internalError(w, err)
+		writeFailed(w, err)
 		return
 	}
 
@@ -145,18 +199,23 @@ func mDelete(w http.ResponseWriter, id string, op *models.RepositoryOperation) {
 }
 
 func create(w http.ResponseWriter, r *http.Request, op *models.RepositoryOperation) {
+	if !claimed(w, r, op.Name, false) {
+		return
+	}
+
 	obj, err := models.ModelParsers[op.Name](r.Body)
 	defer r.Body.Close()
 
 	if err != nil {
-		internalError(w, err)
+		writeFailed(w, err)
 		return
 	}
 
 	if err = op.Create(obj); err != nil {
-		internalError(w, err)
+		writeFailed(w, err)
 		return
 	}
+	setETag(w, obj)
 
 	if err := json.NewEncoder(w).Encode(obj); err != nil {
 		internalError(w, err)
@@ -170,26 +229,84 @@ func update(w http.ResponseWriter, r *http.Request, id string, op *models.Reposi
 		badRequest(w, "invalid uuid")
 		return
 	}
+	oid := models.ObjectID(uid)
+	if !checkStored(w, r, oid, op) || !claimed(w, r, op.Name, false) {
+		return
+	}
 
 	obj, err := models.ModelParsers[op.Name](r.Body)
 	defer r.Body.Close()
 
 	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+	obj.SetID(&oid)
+
+	version, err := ifMatchVersion(r, oid, op)
+	if err == nil {
+		err = op.Update(obj, version)
+	}
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+	setETag(w, obj)
+
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
 		internalError(w, err)
 		return
 	}
+}
+
+// mergePatchTypes are the content types PATCH accepts, an empty one is
+// read as a merge patch too.
+var mergePatchTypes = map[string]bool{
+	"":                             true,
+	"application/json":             true,
+	"application/merge-patch+json": true,
+}
+
+func patch(w http.ResponseWriter, r *http.Request, id string, op *models.RepositoryOperation) {
+	defer r.Body.Close()
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		badRequest(w, "invalid uuid")
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !mergePatchTypes[contentType] {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "unsupported_media_type",
+		})
+		return
+	}
+
 	oid := models.ObjectID(uid)
-	obj.SetID(&oid)
+	if !checkStored(w, r, oid, op) || !claimed(w, r, op.Name, true) {
+		return
+	}
 
-	if err = op.Update(obj); err != nil {
-		var notExists *models.NotExistsError
-		if errors.As(err, &notExists) {
-			notFound(w)
+	version, err := ifMatchVersion(r, oid, op)
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+
+	obj, err := op.Patch(oid, r.Body, version)
+	if err != nil {
+		var patchErr *models.PatchError
+		if errors.As(err, &patchErr) {
+			badRequest(w, patchErr.Error())
 			return
 		}
-		internalError(w, err)
+		writeFailed(w, err)
 		return
 	}
+	setETag(w, obj)
 
 	if err := json.NewEncoder(w).Encode(obj); err != nil {
 		internalError(w, err)
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"notes/api/models"
+)
+
+type bodyKind int
+
+const (
+	noBody bodyKind = iota
+	objectBody
+	mergePatchBody
+	pageBody
+	textBody
+	// secretBody is an object with the secret of a token.
+	secretBody
+)
+
+// operationDoc describes a route in the OpenAPI document.
+type operationDoc struct {
+	summary string
+	// model of the objects in request and response bodies.
+	model    models.ModelName
+	request  bodyKind
+	response bodyKind
+	// status of a successful response.
+	status int
+	// errors lists error statuses besides 500, and 401 unless public. 412
+	// adds an If-Match header, 304 an If-None-Match header.
+	errors []int
+	// public routes are served without a bearer token.
+	public bool
+	// headers describe response headers by name.
+	headers map[string]string
+}
+
+type route struct {
+	method string
+	path   string
+	doc    operationDoc
+}
+
+// router registers handlers on mux and keeps every route to describe it.
+// Requests to routes which aren't public are authenticated. Handlers are
+// only added with handle, so the document can't miss one.
+type router struct {
+	mux        *http.ServeMux
+	routes     []route
+	repository *models.ModelsRepositry
+}
+
+func (rt *router) handle(method, path string, doc operationDoc, handler http.HandlerFunc) {
+	rt.routes = append(rt.routes, route{method, path, doc})
+	rt.mux.HandleFunc(method+" "+path, func(w http.ResponseWriter, r *http.Request) {
+		if doc.response != textBody {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		if !doc.public {
+			var err error
+			if r, err = authenticate(r, rt.repository); err != nil {
+				if errors.Is(err, models.ErrInvalidToken) {
+					unauthorized(w)
+					return
+				}
+				internalError(w, err)
+				return
+			}
+		}
+		handler(w, r)
+	})
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+func newRouter(repository *models.ModelsRepositry) *router {
+	rt := &router{mux: http.NewServeMux(), repository: repository}
+
+	rt.handle(http.MethodGet, "/ping", operationDoc{
+		summary:  "Check the server is up",
+		response: textBody,
+		status:   http.StatusOK,
+		public:   true,
+	}, ping)
+
+	for _, name := range models.ModelsToServe {
+		op := models.NewRepositoryOperation(name, repository)
+		path := "/" + string(name)
+		objectPath := path + "/{id}"
+
+		rt.handle(http.MethodGet, path, operationDoc{
+			summary:  fmt.Sprintf("List %s objects", name),
+			model:    name,
+			response: pageBody,
+			status:   http.StatusOK,
+			errors:   []int{http.StatusBadRequest},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			list(w, r, op)
+		})
+		if name == models.AccountModel {
+			rt.handle(http.MethodPost, path, operationDoc{
+				summary:  fmt.Sprintf("Sign up as a new %s", name),
+				model:    name,
+				request:  objectBody,
+				response: objectBody,
+				status:   http.StatusOK,
+				errors:   []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+				public:   true,
+				headers:  map[string]string{tokenHeader: "Secret of the first token of the " + string(name) + "."},
+			}, func(w http.ResponseWriter, r *http.Request) {
+				signUp(w, r, op, repository)
+			})
+		} else {
+			rt.handle(http.MethodPost, path, operationDoc{
+				summary:  fmt.Sprintf("Create a %s", name),
+				model:    name,
+				request:  objectBody,
+				response: objectBody,
+				status:   http.StatusOK,
+				errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusUnprocessableEntity},
+			}, func(w http.ResponseWriter, r *http.Request) {
+				create(w, r, op)
+			})
+		}
+
+		rt.handle(http.MethodGet, objectPath, operationDoc{
+			summary:  fmt.Sprintf("Get a %s", name),
+			model:    name,
+			response: objectBody,
+			status:   http.StatusOK,
+			errors:   []int{http.StatusNotModified, http.StatusBadRequest, http.StatusNotFound},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			get(w, r, r.PathValue("id"), op)
+		})
+		rt.handle(http.MethodPut, objectPath, operationDoc{
+			summary:  fmt.Sprintf("Replace a %s", name),
+			model:    name,
+			request:  objectBody,
+			response: objectBody,
+			status:   http.StatusOK,
+			errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusUnprocessableEntity},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			update(w, r, r.PathValue("id"), op)
+		})
+		rt.handle(http.MethodPatch, objectPath, operationDoc{
+			summary:  fmt.Sprintf("Update a %s with a merge patch", name),
+			model:    name,
+			request:  mergePatchBody,
+			response: objectBody,
+			status:   http.StatusOK,
+			errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			patch(w, r, r.PathValue("id"), op)
+		})
+		rt.handle(http.MethodDelete, objectPath, operationDoc{
+			summary: fmt.Sprintf("Delete a %s", name),
+			status:  http.StatusNoContent,
+			errors:  []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed},
+		}, func(w http.ResponseWriter, r *http.Request) {
+			mDelete(w, r, r.PathValue("id"), op)
+		})
+	}
+
+	handleRelations(rt, repository)
+	handleTokens(rt, repository)
+
+	document := map[string]any{}
+	rt.handle(http.MethodGet, "/openapi.json", operationDoc{
+		summary:  "Get this OpenAPI document",
+		response: objectBody,
+		status:   http.StatusOK,
+		public:   true,
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(document); err != nil {
+			internalError(w, err)
+		}
+	})
+	document = rt.openAPI()
+
+	return rt
+}
@@ -6,18 +6,30 @@ import (
 )
 
 type server struct {
-	ip   [4]byte
-	port uint16
+	ip      [4]byte
+	port    uint16
+	dataDir string
 }
 
 func (s *server) Run() {
-	mux := rootMux()
+	repository, err := openRepository(s.dataDir)
+	if err != nil {
+		logger.Error("Failed open repository", "dir", s.dataDir, "error", err)
+		return
+	}
+	defer repository.Close()
 
-	http.ListenAndServe(s.url(), mux)
+	mux := rootMux(repository)
+
+	if err := http.ListenAndServe(s.url(), mux); err != nil {
+		logger.Error("Server stopped", "error", err)
+	}
 }
 
-func NewServer(ip [4]byte, port uint16) server {
-	return server{ip, port}
+// NewServer creates a server keeping its objects in dataDir, or only in
+// memory when dataDir is empty.
+func NewServer(ip [4]byte, port uint16, dataDir string) server {
+	return server{ip, port, dataDir}
 }
 
 func (s *server) url() string {
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"notes/api/models"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/uuid"
@@ -16,19 +17,45 @@ import (
 )
 
 func setupTestServer() *httptest.Server {
-	mux := rootMux()
-	return httptest.NewServer(mux)
+	repository, err := models.NewModelsRepository(models.NewUuidGenerator(), models.ModelsToRegister)
+	if err != nil {
+		panic(err)
+	}
+	return httptest.NewServer(rootMux(repository))
 }
 
-func makeRequest(method, url, body string) (*http.Response, error) {
+// makeRequest sends body as JSON, authenticated by token unless it's empty.
+func makeRequest(token, method, url, body string) (*http.Response, error) {
 	req, err := http.NewRequest(method, url, strings.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	return http.DefaultClient.Do(req)
 }
 
+type listResponse[T any] struct {
+	Results    []T     `json:"results"`
+	NextCursor *string `json:"next_cursor"`
+	Total      int     `json:"total"`
+}
+
+// createAuthor signs up an author, returning them with their first token.
+func createAuthor(t *testing.T, url, username string) (models.Author, string) {
+	resp, err := makeRequest("", http.MethodPost, url+"/author", `{"username": "`+username+`"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	token := resp.Header.Get("X-Auth-Token")
+	require.NotEmpty(t, token)
+
+	var author models.Author
+	decodeJSON(t, resp.Body, &author)
+	return author, token
+}
+
 func decodeJSON(t *testing.T, body io.Reader, target interface{}) {
 	err := json.NewDecoder(body).Decode(target)
 	require.NoError(t, err)
@@ -38,7 +65,7 @@ func TestPingRoute(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
 
-	resp, err := http.Get(server.URL + "/ping")
+	resp, err := makeRequest("", http.MethodGet, server.URL+"/ping", "")
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -53,10 +80,12 @@ func TestNoteRoutes(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
 
+	author, token := createAuthor(t, server.URL, "note_writer")
+
 	var createdNote models.Note
 	t.Run("Create", func(t *testing.T) {
-		payload := `{"name": "My First Note", "description": "This is the first note", "author_id": "` + uuid.New().String() + `"}`
-		resp, err := makeRequest(http.MethodPost, server.URL+"/note", payload)
+		payload := `{"name": "My First Note", "description": "This is the first note", "author_id": "` + author.ID.String() + `"}`
+		resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", payload)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 		decodeJSON(t, resp.Body, &createdNote)
@@ -64,7 +93,7 @@ func TestNoteRoutes(t *testing.T) {
 	})
 
 	t.Run("Get", func(t *testing.T) {
-		resp, err := http.Get(server.URL + "/note/" + createdNote.ID.String())
+		resp, err := makeRequest(token, http.MethodGet, server.URL+"/note/"+createdNote.ID.String(), "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -74,18 +103,18 @@ func TestNoteRoutes(t *testing.T) {
 	})
 
 	t.Run("List", func(t *testing.T) {
-		resp, err := http.Get(server.URL + "/note")
+		resp, err := makeRequest(token, http.MethodGet, server.URL+"/note", "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-		var list map[string][]models.Note
+		var list listResponse[models.Note]
 		decodeJSON(t, resp.Body, &list)
-		assert.GreaterOrEqual(t, len(list["results"]), 1)
+		assert.GreaterOrEqual(t, len(list.Results), 1)
 	})
 
 	t.Run("Update", func(t *testing.T) {
 		payload := `{"name": "Updated Note", "description": "Updated Desc"}`
-		resp, err := makeRequest(http.MethodPut, server.URL+"/note/"+createdNote.ID.String(), payload)
+		resp, err := makeRequest(token, http.MethodPut, server.URL+"/note/"+createdNote.ID.String(), payload)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -96,11 +125,11 @@ func TestNoteRoutes(t *testing.T) {
 	})
 
 	t.Run("Delete", func(t *testing.T) {
-		resp, err := makeRequest(http.MethodDelete, server.URL+"/note/"+createdNote.ID.String(), "")
+		resp, err := makeRequest(token, http.MethodDelete, server.URL+"/note/"+createdNote.ID.String(), "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 
-		resp, err = http.Get(server.URL + "/note/" + createdNote.ID.String())
+		resp, err = makeRequest(token, http.MethodGet, server.URL+"/note/"+createdNote.ID.String(), "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 	})
@@ -111,16 +140,18 @@ func TestAuthorRoutes(t *testing.T) {
 	defer server.Close()
 
 	var created models.Author
+	var token string
 	t.Run("Create", func(t *testing.T) {
 		payload := `{"username": "testuser", "firstname": "John", "secondname": "Doe"}`
-		resp, err := makeRequest(http.MethodPost, server.URL+"/author", payload)
+		resp, err := makeRequest("", http.MethodPost, server.URL+"/author", payload)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		token = resp.Header.Get("X-Auth-Token")
 		decodeJSON(t, resp.Body, &created)
 	})
 
 	t.Run("Get", func(t *testing.T) {
-		resp, err := http.Get(server.URL + "/author/" + created.ID.String())
+		resp, err := makeRequest(token, http.MethodGet, server.URL+"/author/"+created.ID.String(), "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -130,18 +161,18 @@ func TestAuthorRoutes(t *testing.T) {
 	})
 
 	t.Run("List", func(t *testing.T) {
-		resp, err := http.Get(server.URL + "/author")
+		resp, err := makeRequest(token, http.MethodGet, server.URL+"/author", "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-		var list map[string][]models.Author
+		var list listResponse[models.Author]
 		decodeJSON(t, resp.Body, &list)
-		assert.GreaterOrEqual(t, len(list["results"]), 1)
+		assert.GreaterOrEqual(t, len(list.Results), 1)
 	})
 
 	t.Run("Update", func(t *testing.T) {
 		payload := `{"username": "updateduser", "secondname": "Smith"}`
-		resp, err := makeRequest(http.MethodPut, server.URL+"/author/"+created.ID.String(), payload)
+		resp, err := makeRequest(token, http.MethodPut, server.URL+"/author/"+created.ID.String(), payload)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -152,7 +183,7 @@ func TestAuthorRoutes(t *testing.T) {
 	})
 
 	t.Run("Delete", func(t *testing.T) {
-		resp, err := makeRequest(http.MethodDelete, server.URL+"/author/"+created.ID.String(), "")
+		resp, err := makeRequest(token, http.MethodDelete, server.URL+"/author/"+created.ID.String(), "")
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 	})
@@ -161,9 +192,10 @@ func TestAuthorRoutes(t *testing.T) {
 func TestInvalidUUID(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
+	_, token := createAuthor(t, server.URL, "caller")
 
 	checkError := func(method, path string, body string) {
-		resp, err := makeRequest(method, server.URL+path, body)
+		resp, err := makeRequest(token, method, server.URL+path, body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 
@@ -181,10 +213,11 @@ func TestInvalidUUID(t *testing.T) {
 func TestNotFound(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
+	_, token := createAuthor(t, server.URL, "caller")
 	id := uuid.New().String()
 
 	checkNotFound := func(method, path, body string) {
-		resp, err := makeRequest(method, server.URL+path, body)
+		resp, err := makeRequest(token, method, server.URL+path, body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
@@ -203,9 +236,7 @@ func TestMethodNotAllowed(t *testing.T) {
 	defer server.Close()
 
 	checkMethodNotAllowed := func(method, path string) {
-		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(`{}`))
-		require.NoError(t, err)
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := makeRequest("", method, server.URL+path, `{}`)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
 	}
@@ -215,51 +246,610 @@ func TestMethodNotAllowed(t *testing.T) {
 	checkMethodNotAllowed(http.MethodPost, "/note/"+uuid.New().String())
 }
 
+type validationResponse struct {
+	Error  string              `json:"error"`
+	Errors []models.FieldError `json:"errors"`
+}
+
 func TestBadRequestOnCreateUpdate(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
+	_, token := createAuthor(t, server.URL, "caller")
+
+	assertInvalid := func(t *testing.T, resp *http.Response, expected ...models.FieldError) {
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		var body validationResponse
+		decodeJSON(t, resp.Body, &body)
+		assert.Equal(t, "validation_failed", body.Error)
+		require.Len(t, body.Errors, len(expected))
+		for i, fe := range expected {
+			assert.Equal(t, fe.Field, body.Errors[i].Field)
+			assert.Equal(t, fe.Code, body.Errors[i].Code)
+			assert.NotEmpty(t, body.Errors[i].Message)
+		}
+	}
 
 	t.Run("CreateNoteInvalidPayload", func(t *testing.T) {
-		resp, err := http.Post(server.URL+"/note", "application/json", strings.NewReader(`{"description":"Missing name"}`))
+		resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", `{"description":"Missing name"}`)
 		require.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assertInvalid(t, resp, models.FieldError{Field: "name", Code: "required"})
 	})
 
 	t.Run("UpdateNoteInvalidPayload", func(t *testing.T) {
 		// First, create a valid note
-		valid := models.Note{Name: "Test", AuthorId: &models.ObjectID{}}
+		valid := models.Note{Name: "Test"}
 		var buf bytes.Buffer
 		require.NoError(t, json.NewEncoder(&buf).Encode(valid))
-		resp, err := http.Post(server.URL+"/note", "application/json", &buf)
+		resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", buf.String())
 		require.NoError(t, err)
 
 		var note models.Note
 		decodeJSON(t, resp.Body, &note)
 
 		// Then, try to update it with an invalid payload
-		resp, err = makeRequest(http.MethodPut, server.URL+"/note/"+note.ID.String(), `{"author_id":"not-a-uuid"}`)
+		resp, err = makeRequest(token, http.MethodPut, server.URL+"/note/"+note.ID.String(), `{"author_id":"not-a-uuid"}`)
 		require.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assertInvalid(t, resp,
+			models.FieldError{Field: "author_id", Code: "type"},
+			models.FieldError{Field: "name", Code: "required"},
+		)
 	})
 
 	t.Run("CreateAuthorInvalidPayload", func(t *testing.T) {
-		resp, err := http.Post(server.URL+"/author", "application/json", strings.NewReader(`{"firstname":"John"}`))
+		resp, err := makeRequest(token, http.MethodPost, server.URL+"/author", `{"firstname":"John"}`)
 		require.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assertInvalid(t, resp, models.FieldError{Field: "username", Code: "required"})
 	})
 
 	t.Run("UpdateAuthorInvalidPayload", func(t *testing.T) {
-		author := models.Author{Username: "test"}
-		var buf bytes.Buffer
-		require.NoError(t, json.NewEncoder(&buf).Encode(author))
-		resp, err := http.Post(server.URL+"/author", "application/json", &buf)
+		created, token := createAuthor(t, server.URL, "test")
+
+		resp, err := makeRequest(token, http.MethodPut, server.URL+"/author/"+created.ID.String(), `{"username":123}`)
 		require.NoError(t, err)
+		assertInvalid(t, resp, models.FieldError{Field: "username", Code: "type"})
+	})
 
-		var created models.Author
-		decodeJSON(t, resp.Body, &created)
+	t.Run("RulesPerModel", func(t *testing.T) {
+		long := strings.Repeat("x", 201)
+		resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", `{"name": "`+long+`"}`)
+		require.NoError(t, err)
+		assertInvalid(t, resp, models.FieldError{Field: "name", Code: "max_length"})
 
-		resp, err = makeRequest(http.MethodPut, server.URL+"/author/"+created.ID.String(), `{"username":123}`)
+		resp, err = makeRequest(token, http.MethodPost, server.URL+"/author", `{"username": "no spaces", "firstname": "`+long+`"}`)
 		require.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assertInvalid(t, resp,
+			models.FieldError{Field: "username", Code: "format"},
+			models.FieldError{Field: "firstname", Code: "max_length"},
+		)
+	})
+
+	t.Run("UnparseableBody", func(t *testing.T) {
+		for _, body := range []string{`{"name":`, `[]`, `null`, ``} {
+			resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+
+			var payload map[string]string
+			decodeJSON(t, resp.Body, &payload)
+			assert.Equal(t, "bad_request", payload["error"])
+		}
 	})
 }
+
+func TestFileStorageSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	open := func() (*models.ModelsRepositry, *httptest.Server) {
+		repository, err := models.NewModelsRepository(models.NewUuidGenerator(), models.ModelsToRegister, models.WithFileStorage(dir))
+		require.NoError(t, err)
+		return repository, httptest.NewServer(rootMux(repository))
+	}
+
+	repository, server := open()
+	created, token := createAuthor(t, server.URL, "keeper")
+	server.Close()
+	require.NoError(t, repository.Close())
+
+	repository, server = open()
+	defer repository.Close()
+	defer server.Close()
+
+	resp, err := makeRequest(token, http.MethodGet, server.URL+"/author/"+created.ID.String(), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "tokens survive too")
+	var fetched models.Author
+	decodeJSON(t, resp.Body, &fetched)
+	assert.Equal(t, created, fetched)
+}
+
+func TestConcurrentNoteWrites(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+	_, token := createAuthor(t, server.URL, "caller")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", `{"name": "note"}`)
+				if !assert.NoError(t, err) {
+					return
+				}
+				var note models.Note
+				decodeJSON(t, resp.Body, &note)
+				resp.Body.Close()
+
+				url := server.URL + "/note/" + note.ID.String()
+				resp, err = makeRequest(token, http.MethodPut, url, `{"name": "updated"}`)
+				if assert.NoError(t, err) {
+					assert.Equal(t, http.StatusOK, resp.StatusCode)
+					resp.Body.Close()
+				}
+				resp, err = makeRequest(token, http.MethodGet, server.URL+"/note", "")
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+				}
+				resp, err = makeRequest(token, http.MethodDelete, url, "")
+				if assert.NoError(t, err) {
+					assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+					resp.Body.Close()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp, err := makeRequest(token, http.MethodGet, server.URL+"/note", "")
+	require.NoError(t, err)
+	var list listResponse[models.Note]
+	decodeJSON(t, resp.Body, &list)
+	assert.Empty(t, list.Results)
+}
+
+func TestListPagination(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	author, token := createAuthor(t, server.URL, "lister")
+	authorID := author.ID.String()
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		payload := `{"name": "` + name + `", "author_id": "` + authorID + `"}`
+		resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", payload)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	_, otherToken := createAuthor(t, server.URL, "other")
+	_, err := makeRequest(otherToken, http.MethodPost, server.URL+"/note", `{"name": "other author"}`)
+	require.NoError(t, err)
+
+	query := "/note?limit=2&sort=-created&author_id=" + authorID
+	resp, err := makeRequest(token, http.MethodGet, server.URL+query, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var first listResponse[models.Note]
+	decodeJSON(t, resp.Body, &first)
+	assert.Equal(t, 3, first.Total)
+	require.Len(t, first.Results, 2)
+	assert.Equal(t, "gamma", first.Results[0].Name)
+	require.NotNil(t, first.NextCursor)
+
+	resp, err = makeRequest(token, http.MethodGet, server.URL+query+"&cursor="+*first.NextCursor, "")
+	require.NoError(t, err)
+	var second listResponse[models.Note]
+	decodeJSON(t, resp.Body, &second)
+	require.Len(t, second.Results, 1)
+	assert.Equal(t, "alpha", second.Results[0].Name)
+	assert.Nil(t, second.NextCursor)
+
+	resp, err = makeRequest(token, http.MethodGet, server.URL+"/note?name_contains=ALP", "")
+	require.NoError(t, err)
+	var filtered listResponse[models.Note]
+	decodeJSON(t, resp.Body, &filtered)
+	assert.Equal(t, 1, filtered.Total)
+
+	for _, bad := range []string{"limit=0", "sort=size", "colour=red", "cursor=xyz"} {
+		resp, err := makeRequest(token, http.MethodGet, server.URL+"/note?"+bad, "")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, bad)
+	}
+}
+
+func TestReferentialIntegrity(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	author, token := createAuthor(t, server.URL, "owner")
+	resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", `{"name": "owned", "author_id": "`+author.ID.String()+`"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var note models.Note
+	decodeJSON(t, resp.Body, &note)
+
+	resp, err = makeRequest(token, http.MethodDelete, server.URL+"/author/"+author.ID.String(), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = makeRequest(token, http.MethodDelete, server.URL+"/note/"+note.ID.String(), "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, err = makeRequest(token, http.MethodDelete, server.URL+"/author/"+author.ID.String(), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = makeRequest(token, http.MethodGet, server.URL+"/note", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "tokens are deleted with their author")
+}
+
+func TestAuthorNotesRoutes(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	author, token := createAuthor(t, server.URL, "nested")
+	other, otherToken := createAuthor(t, server.URL, "other")
+	authorNotes := server.URL + "/author/" + author.ID.String() + "/note"
+
+	resp, err := makeRequest(token, http.MethodPost, authorNotes, `{"name": "mine", "author_id": "`+other.ID.String()+`"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var created models.Note
+	decodeJSON(t, resp.Body, &created)
+	assert.Equal(t, *author.ID, *created.AuthorId, "path author wins over body")
+
+	_, err = makeRequest(otherToken, http.MethodPost, server.URL+"/note", `{"name": "theirs", "author_id": "`+other.ID.String()+`"}`)
+	require.NoError(t, err)
+
+	resp, err = makeRequest(token, http.MethodGet, authorNotes, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list listResponse[models.Note]
+	decodeJSON(t, resp.Body, &list)
+	require.Len(t, list.Results, 1)
+	assert.Equal(t, "mine", list.Results[0].Name)
+
+	otherNotes := server.URL + "/author/" + other.ID.String() + "/note"
+	resp, err = makeRequest(token, http.MethodGet, otherNotes, "")
+	require.NoError(t, err)
+	var hidden listResponse[models.Note]
+	decodeJSON(t, resp.Body, &hidden)
+	assert.Empty(t, hidden.Results, "notes of other authors are private")
+	resp, err = makeRequest(token, http.MethodPost, otherNotes, `{"name": "planted"}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	missing := server.URL + "/author/" + uuid.New().String() + "/note"
+	resp, err = makeRequest(token, http.MethodGet, missing, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, err = makeRequest(token, http.MethodPost, missing, `{"name": "lost"}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = makeRequest(token, http.MethodPost, authorNotes, `[]`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, err = makeRequest(token, http.MethodDelete, authorNotes, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestPatchNote(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	author, token := createAuthor(t, server.URL, "patcher")
+	resp, err := makeRequest(token, http.MethodPost, server.URL+"/note", `{"name": "draft", "description": "text", "author_id": "`+author.ID.String()+`"}`)
+	require.NoError(t, err)
+	var created models.Note
+	decodeJSON(t, resp.Body, &created)
+	url := server.URL + "/note/" + created.ID.String()
+
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(`{"description": null}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var patched models.Note
+	decodeJSON(t, resp.Body, &patched)
+	assert.Equal(t, "draft", patched.Name)
+	assert.Nil(t, patched.Description)
+	assert.Equal(t, *author.ID, *patched.AuthorId)
+	assert.True(t, created.Created.Equal(*patched.Created))
+
+	resp, err = makeRequest(token, http.MethodPut, url, `{"name": "replaced"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var replaced models.Note
+	decodeJSON(t, resp.Body, &replaced)
+	assert.Nil(t, replaced.Description, "PUT replaces the whole object")
+	assert.Equal(t, *author.ID, *replaced.AuthorId, "but the note stays with its author")
+	require.NotNil(t, replaced.Created)
+	assert.True(t, created.Created.Equal(*replaced.Created), "PUT keeps created")
+
+	resp, err = makeRequest(token, http.MethodPatch, url, `{"author_id": null}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = makeRequest(token, http.MethodPatch, url, `{"name": null}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp, err = makeRequest(token, http.MethodPatch, url, `["name"]`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = makeRequest(token, http.MethodPatch, server.URL+"/note/"+uuid.New().String(), `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodPatch, url, strings.NewReader(`name=x`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestETags(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+	_, token := createAuthor(t, server.URL, "caller")
+
+	do := func(method, url, body string, header ...string) *http.Response {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := do(http.MethodPost, server.URL+"/note", `{"name": "shared"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	var note models.Note
+	decodeJSON(t, resp.Body, &note)
+	url := server.URL + "/note/" + note.ID.String()
+
+	resp = do(http.MethodGet, url, "")
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	resp = do(http.MethodGet, url, "", "If-None-Match", `"1"`)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	resp = do(http.MethodGet, url, "", "If-None-Match", `W/"0", W/"1"`)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	resp = do(http.MethodGet, url, "", "If-None-Match", `"0"`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Two clients edit version 1, the second one must not overwrite.
+	resp = do(http.MethodPut, url, `{"name": "first"}`, "If-Match", `"1"`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+	resp = do(http.MethodPut, url, `{"name": "second"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = do(http.MethodPatch, url, `{"name": "second"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = do(http.MethodDelete, url, "", "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = do(http.MethodPut, url, `{"name": "weak"}`, "If-Match", `W/"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp = do(http.MethodPatch, url, `{"description": "more"}`, "If-Match", `"1", "2"`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+	resp = do(http.MethodPut, url, `{"name": "any", "version": 1}`, "If-Match", "*")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"4"`, resp.Header.Get("ETag"), "version in the body is ignored")
+	resp = do(http.MethodPut, url, `{"name": "unconditional"}`)
+	assert.Equal(t, `"5"`, resp.Header.Get("ETag"))
+
+	resp = do(http.MethodDelete, url, "", "If-Match", `"5"`)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(http.MethodDelete, url, "", "If-Match", `"5"`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAuthentication(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+	_, token := createAuthor(t, server.URL, "caller")
+
+	for _, auth := range []string{"", "Bearer", "Bearer nt_unknown", "Basic " + token} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/note", nil)
+		require.NoError(t, err)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, auth)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Bearer", auth)
+	}
+
+	resp, err := makeRequest(token, http.MethodGet, server.URL+"/note", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTokens(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+	author, token := createAuthor(t, server.URL, "holder")
+	other, otherToken := createAuthor(t, server.URL, "other")
+	tokens := server.URL + "/author/" + author.ID.String() + "/token"
+
+	resp, err := makeRequest(token, http.MethodPost, tokens, `{"name": "laptop", "author_id": "`+other.ID.String()+`"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var issued map[string]any
+	decodeJSON(t, resp.Body, &issued)
+	assert.Equal(t, "laptop", issued["name"])
+	assert.Equal(t, author.ID.String(), issued["author_id"], "tokens are issued for the path author")
+	assert.NotContains(t, issued, "hash")
+	secret, _ := issued["secret"].(string)
+	require.NotEmpty(t, secret)
+
+	resp, err = makeRequest(secret, http.MethodGet, tokens, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list listResponse[map[string]any]
+	decodeJSON(t, resp.Body, &list)
+	require.Len(t, list.Results, 2, "the sign up token and the new one")
+	for _, listed := range list.Results {
+		assert.NotContains(t, listed, "hash")
+		assert.NotContains(t, listed, "secret")
+	}
+
+	otherTokens := server.URL + "/author/" + other.ID.String() + "/token"
+	resp, err = makeRequest(token, http.MethodGet, otherTokens, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, err = makeRequest(token, http.MethodPost, otherTokens, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, err = makeRequest(otherToken, http.MethodDelete, otherTokens+"/"+issued["id"].(string), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "a token is only revoked by its author")
+
+	resp, err = makeRequest(token, http.MethodDelete, tokens+"/"+issued["id"].(string), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, err = makeRequest(secret, http.MethodGet, tokens, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "revoked tokens stop working")
+	resp, err = makeRequest(token, http.MethodGet, tokens, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAuthorization(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+	alice, aliceToken := createAuthor(t, server.URL, "alice")
+	bob, bobToken := createAuthor(t, server.URL, "bob")
+
+	resp, err := makeRequest(aliceToken, http.MethodPost, server.URL+"/note", `{"name": "diary"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var note models.Note
+	decodeJSON(t, resp.Body, &note)
+	assert.Equal(t, *alice.ID, *note.AuthorId, "notes belong to their creator")
+	url := server.URL + "/note/" + note.ID.String()
+
+	resp, err = makeRequest(aliceToken, http.MethodPost, server.URL+"/note", `{"name": "forged", "author_id": "`+bob.ID.String()+`"}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		resp, err := makeRequest(bobToken, method, url, `{"name": "taken"}`)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "%s of a note of another author", method)
+	}
+	for _, query := range []string{"", "?author_id=" + alice.ID.String()} {
+		resp, err := makeRequest(bobToken, http.MethodGet, server.URL+"/note"+query, "")
+		require.NoError(t, err)
+		var list listResponse[models.Note]
+		decodeJSON(t, resp.Body, &list)
+		assert.Empty(t, list.Results, query)
+	}
+
+	resp, err = makeRequest(aliceToken, http.MethodPut, url, `{"name": "gift", "author_id": "`+bob.ID.String()+`"}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	aliceURL := server.URL + "/author/" + alice.ID.String()
+	resp, err = makeRequest(bobToken, http.MethodGet, aliceURL, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "authors are visible to everyone")
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		resp, err := makeRequest(bobToken, method, aliceURL, `{"username": "mallory"}`)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "%s of another author", method)
+	}
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	repository, err := models.NewModelsRepository(models.NewUuidGenerator(), models.ModelsToRegister)
+	require.NoError(t, err)
+	rt := newRouter(repository)
+	server := httptest.NewServer(rt)
+	defer server.Close()
+
+	resp, err := makeRequest("", http.MethodGet, server.URL+"/openapi.json", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Summary    string                    `json:"summary"`
+			Responses  map[string]map[string]any `json:"responses"`
+			Parameters []struct {
+				Name   string `json:"name"`
+				Schema struct {
+					Enum []string `json:"enum"`
+				} `json:"schema"`
+			} `json:"parameters"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	decodeJSON(t, resp.Body, &document)
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+
+	require.NotEmpty(t, rt.routes)
+	for _, r := range rt.routes {
+		operation, ok := document.Paths[r.path][strings.ToLower(r.method)]
+		if assert.True(t, ok, "%s %s is not described", r.method, r.path) {
+			assert.NotEmpty(t, operation.Summary, "%s %s", r.method, r.path)
+			assert.Contains(t, operation.Responses, "500", "%s %s", r.method, r.path)
+		}
+	}
+
+	// A handler answering a method the document leaves out would have
+	// bypassed handle.
+	methods := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	for path, operations := range document.Paths {
+		url := server.URL + strings.ReplaceAll(path, "{id}", uuid.New().String())
+		for _, method := range methods {
+			if _, ok := operations[strings.ToLower(method)]; ok {
+				continue
+			}
+			resp, err := makeRequest("", method, url, `{}`)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode, "%s %s is not described", method, path)
+		}
+	}
+
+	// Every documented sort must be accepted by the list endpoint.
+	author, token := createAuthor(t, server.URL, "openapi")
+	for path, operations := range document.Paths {
+		for _, p := range operations["get"].Parameters {
+			if p.Name != "sort" {
+				continue
+			}
+			for _, sort := range p.Schema.Enum {
+				resp, err := makeRequest(token, http.MethodGet, server.URL+strings.ReplaceAll(path, "{id}", author.ID.String())+"?sort="+sort, "")
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode, "GET %s?sort=%s", path, sort)
+			}
+		}
+	}
+
+	note := document.Components.Schemas["Note"].Properties
+	assert.Equal(t, map[string]any{"type": "string", "format": "uuid", "nullable": true}, note["author_id"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time", "nullable": true}, note["created"])
+	assert.Equal(t, map[string]any{"type": "string"}, note["name"])
+	assert.Equal(t, true, note["version"]["readOnly"])
+	assert.Contains(t, document.Components.Schemas, "ValidationError")
+}
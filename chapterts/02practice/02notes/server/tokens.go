@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"notes/api/models"
+)
+
+// tokenHeader carries the first token of an author signing up.
+const tokenHeader = "X-Auth-Token"
+
+// issuedToken is a token with its secret, only returned when it's issued.
+type issuedToken struct {
+	*models.Token
+	Secret string `json:"secret"`
+}
+
+// handleTokens serves the tokens of an author under /{account}/{id}/token,
+// only to the author themselves.
+func handleTokens(rt *router, repository *models.ModelsRepositry) {
+	op := models.NewRepositoryOperation(models.TokenModel, repository)
+	path := fmt.Sprintf("/%s/{id}/token", models.AccountModel)
+
+	rt.handle(http.MethodGet, path, operationDoc{
+		summary:  "List the tokens of an author",
+		model:    op.Name,
+		response: pageBody,
+		status:   http.StatusOK,
+		errors:   []int{http.StatusBadRequest, http.StatusForbidden},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := ownPathID(w, r); ok {
+			listTokens(w, r, id, op)
+		}
+	})
+	rt.handle(http.MethodPost, path, operationDoc{
+		summary:  "Issue a token for an author, its secret is only returned now",
+		model:    op.Name,
+		request:  objectBody,
+		response: secretBody,
+		status:   http.StatusOK,
+		errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusUnprocessableEntity},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := ownPathID(w, r); ok {
+			issueToken(w, r, id, repository)
+		}
+	})
+	rt.handle(http.MethodDelete, path+"/{token}", operationDoc{
+		summary: "Revoke a token of an author",
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		id, ok := ownPathID(w, r)
+		if !ok {
+			return
+		}
+		if tokenID, ok := pathID(w, r, "token"); ok {
+			revokeToken(w, id, tokenID, op)
+		}
+	})
+}
+
+// ownPathID returns the author id of the path if it's the caller.
+func ownPathID(w http.ResponseWriter, r *http.Request) (models.ObjectID, bool) {
+	id, ok := pathID(w, r, "id")
+	if ok && id != *callerOf(r).ID {
+		forbidden(w, "tokens of another author")
+		return id, false
+	}
+	return id, ok
+}
+
+func listTokens(w http.ResponseWriter, r *http.Request, id models.ObjectID, op *models.RepositoryOperation) {
+	q, err := models.ParseListQuery(r.URL.Query())
+	if err != nil {
+		queryFailed(w, err)
+		return
+	}
+	q.Filters["author_id"] = id.String()
+
+	page, err := op.Query(q)
+	if err != nil {
+		queryFailed(w, err)
+		return
+	}
+	for i, obj := range page.Results {
+		page.Results[i] = obj.(*models.Token).Redacted()
+	}
+
+	writePage(w, page)
+}
+
+func issueToken(w http.ResponseWriter, r *http.Request, id models.ObjectID, repository *models.ModelsRepositry) {
+	obj, err := models.ModelParsers[models.TokenModel](r.Body)
+	r.Body.Close()
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+
+	token := obj.(*models.Token)
+	token.AuthorId = &id
+	secret, err := repository.IssueToken(token)
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+	setETag(w, token)
+
+	if err := json.NewEncoder(w).Encode(issuedToken{token.Redacted(), secret}); err != nil {
+		internalError(w, err)
+		return
+	}
+}
+
+func revokeToken(w http.ResponseWriter, id, tokenID models.ObjectID, op *models.RepositoryOperation) {
+	obj, err := op.Get(tokenID)
+	if err == nil && *obj.(*models.Token).AuthorId != id {
+		err = models.NewNotExistsError(op.Name, tokenID)
+	}
+	if err == nil {
+		err = op.Delete(tokenID, 0)
+	}
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// signUp creates an author and issues their first token, so they can
+// authenticate the requests that follow.
+func signUp(w http.ResponseWriter, r *http.Request, op *models.RepositoryOperation, repository *models.ModelsRepositry) {
+	obj, err := models.ModelParsers[op.Name](r.Body)
+	r.Body.Close()
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+
+	if err := op.Create(obj); err != nil {
+		writeFailed(w, err)
+		return
+	}
+	author := obj.(*models.Author)
+	secret, err := repository.IssueToken(&models.Token{Name: "sign up", AuthorId: author.ID})
+	if err != nil {
+		writeFailed(w, err)
+		return
+	}
+	w.Header().Set(tokenHeader, secret)
+	setETag(w, obj)
+
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		internalError(w, err)
+		return
+	}
+}
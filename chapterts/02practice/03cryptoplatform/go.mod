@@ -15,6 +15,6 @@ require (
 
 require (
 	github.com/golang-migrate/migrate/v4 v4.18.3
-	github.com/jmoiron/sqlx v1.4.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/jmoiron/sqlx v1.4.0
+	github.com/lib/pq v1.10.9
 )